@@ -14,15 +14,17 @@ import (
 )
 
 var (
-	branch string
-	token  string
-	dest   string
+	branch    string
+	token     string
+	dest      string
+	codecDest string
 )
 
 func init() {
 	flag.StringVar(&branch, "branch", "", "svm branch to download the artifacts from it's latest workflow run")
 	flag.StringVar(&token, "token", "", "github token for better api privileges")
 	flag.StringVar(&dest, "dest", "", "destination folder")
+	flag.StringVar(&codecDest, "codec-dest", "", "destination folder of the codec wasm; defaults to -dest")
 	flag.Parse()
 }
 
@@ -34,7 +36,10 @@ type archivesDownloadUrl struct {
 }
 
 func main() {
-	log.Printf("%v; branch: %v, token: %v, dest: %v\n", filepath.Base(os.Args[0]), branch, token, dest)
+	if codecDest == "" {
+		codecDest = dest
+	}
+	log.Printf("%v; branch: %v, token: %v, dest: %v, codec-dest: %v\n", filepath.Base(os.Args[0]), branch, token, dest, codecDest)
 
 	runUrl, err := runUrl()
 	noError(err)
@@ -48,7 +53,7 @@ func main() {
 	noError(fetch(archivesUrl.linux, dest))
 	noError(fetch(archivesUrl.macOS, dest))
 	noError(fetch(archivesUrl.windows, dest))
-	noError(fetch(archivesUrl.wasmCodec, dest))
+	noError(fetch(archivesUrl.wasmCodec, codecDest))
 }
 
 func runUrl() (string, error) {
@@ -161,13 +166,13 @@ func archivesUrl(artifactsUrl string) (archivesDownloadUrl, error) {
 	return ret, nil
 }
 
-func fetch(url, name string) error {
-	name, err := download(url)
+func fetch(url, dir string) error {
+	name, err := download(url, dir)
 	if err != nil {
 		return err
 	}
 
-	if err := unzip(name); err != nil {
+	if err := unzip(name, dir); err != nil {
 		return err
 	}
 
@@ -178,7 +183,7 @@ func fetch(url, name string) error {
 	return nil
 }
 
-func download(url string) (string, error) {
+func download(url, dir string) (string, error) {
 	log.Printf("GET %v", url)
 	res, err := http.DefaultClient.Do(req("GET", url))
 	if err != nil {
@@ -186,7 +191,7 @@ func download(url string) (string, error) {
 	}
 	defer res.Body.Close()
 
-	name := filepath.Join(dest, "temp.zip")
+	name := filepath.Join(dir, "temp.zip")
 	file, err := os.Create(name)
 	if err != nil {
 		return "", err
@@ -203,7 +208,7 @@ func download(url string) (string, error) {
 	return name, nil
 }
 
-func unzip(path string) error {
+func unzip(path, dir string) error {
 	r, err := zip.OpenReader(path)
 	if err != nil {
 		return err
@@ -211,7 +216,7 @@ func unzip(path string) error {
 	defer r.Close()
 
 	for _, f := range r.File {
-		name := filepath.Join(dest, f.Name)
+		name := filepath.Join(dir, f.Name)
 		file, err := os.Create(name)
 		if err != nil {
 			return err
@@ -0,0 +1,373 @@
+package codec
+
+import (
+	"fmt"
+	"go-svm/common"
+	"strings"
+)
+
+// ABI markers, as laid out by `svm-abi-layout`. Each encoded value starts with a marker byte.
+// Integer markers are combined with the number of bytes the integer is encoded with; see `encodeInt`.
+const (
+	abiBoolFalse = 0x00
+	abiBoolTrue  = 0x10
+	abiAddress   = 0x40
+
+	abiAmount = 0x01
+	abiI8     = 0x02
+	abiU8     = 0x12
+	abiI16    = 0x22
+	abiU16    = 0x42
+	abiI32    = 0x03
+	abiU32    = 0x43
+	abiI64    = 0x04
+	abiU64    = 0x05
+
+	// abiArray is combined with the length of arrays of up to `maxShortArrayLen` items,
+	// while longer arrays are marked by `abiArrayLong`, followed by their length (1 byte).
+	abiArray     = 0x06
+	abiArrayLong = 0x76
+)
+
+const (
+	maxShortArrayLen = 6
+	maxArrayLen      = 255
+)
+
+// ABIValue is a typed value which can be encoded as calldata, to be decoded by an app's `svm_sdk::CallData`.
+//
+// The implementations are `Bool`, `I8`, `I16`, `I32`, `I64`, `U8`, `U16`, `U32`, `U64`,
+// `Amount`, `Address`, `Bytes` and `Array`.
+type ABIValue interface {
+	// abiType returns the name of the value's type, as used by the codec wasm.
+	// Arrays are named after their items, e.g., `[u32]`.
+	abiType() string
+
+	// encodeABI appends the encoded value to `buf`.
+	encodeABI(buf []byte) ([]byte, error)
+}
+
+type (
+	Bool   bool
+	I8     int8
+	I16    int16
+	I32    int32
+	I64    int64
+	U8     uint8
+	U16    uint16
+	U32    uint32
+	U64    uint64
+	Amount uint64
+
+	Address common.Address
+
+	// Bytes is a byte array, either fixed-size (`[u8; N]`) or dynamic (`Vec<u8>`),
+	// both of which are encoded as an array of `u8` items.
+	Bytes []byte
+
+	// Array is an array of values of the same type, which may be arrays themselves.
+	Array []ABIValue
+)
+
+func (Bool) abiType() string    { return "bool" }
+func (I8) abiType() string      { return "i8" }
+func (I16) abiType() string     { return "i16" }
+func (I32) abiType() string     { return "i32" }
+func (I64) abiType() string     { return "i64" }
+func (U8) abiType() string      { return "u8" }
+func (U16) abiType() string     { return "u16" }
+func (U32) abiType() string     { return "u32" }
+func (U64) abiType() string     { return "u64" }
+func (Amount) abiType() string  { return "amount" }
+func (Address) abiType() string { return "address" }
+func (Bytes) abiType() string   { return "[u8]" }
+
+func (arr Array) abiType() string {
+	itemType := ""
+	for _, item := range arr {
+		if item != nil {
+			itemType = mergeABITypes(itemType, item.abiType())
+		}
+	}
+
+	return "[" + itemType + "]"
+}
+
+func (v Bool) encodeABI(buf []byte) ([]byte, error) {
+	if v {
+		return append(buf, abiBoolTrue), nil
+	}
+	return append(buf, abiBoolFalse), nil
+}
+
+func (v I8) encodeABI(buf []byte) ([]byte, error) {
+	return append(buf, abiI8, byte(v)), nil
+}
+
+func (v I16) encodeABI(buf []byte) ([]byte, error) {
+	return encodeInt(buf, abiI16, uint64(uint16(v))), nil
+}
+
+func (v I32) encodeABI(buf []byte) ([]byte, error) {
+	return encodeInt(buf, abiI32, uint64(uint32(v))), nil
+}
+
+func (v I64) encodeABI(buf []byte) ([]byte, error) {
+	return encodeInt(buf, abiI64, uint64(v)), nil
+}
+
+func (v U8) encodeABI(buf []byte) ([]byte, error) {
+	return append(buf, abiU8, byte(v)), nil
+}
+
+func (v U16) encodeABI(buf []byte) ([]byte, error) {
+	return encodeInt(buf, abiU16, uint64(v)), nil
+}
+
+func (v U32) encodeABI(buf []byte) ([]byte, error) {
+	return encodeInt(buf, abiU32, uint64(v)), nil
+}
+
+func (v U64) encodeABI(buf []byte) ([]byte, error) {
+	return encodeInt(buf, abiU64, uint64(v)), nil
+}
+
+func (v Amount) encodeABI(buf []byte) ([]byte, error) {
+	return encodeInt(buf, abiAmount, uint64(v)), nil
+}
+
+func (v Address) encodeABI(buf []byte) ([]byte, error) {
+	buf = append(buf, abiAddress)
+	return append(buf, v[:]...), nil
+}
+
+func (v Bytes) encodeABI(buf []byte) ([]byte, error) {
+	buf, err := encodeArrayLen(buf, len(v))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range v {
+		buf = append(buf, abiU8, b)
+	}
+
+	return buf, nil
+}
+
+func (arr Array) encodeABI(buf []byte) ([]byte, error) {
+	buf, err := encodeArrayLen(buf, len(arr))
+	if err != nil {
+		return nil, err
+	}
+
+	itemType := ""
+	for i, item := range arr {
+		if item == nil {
+			return nil, fmt.Errorf("array item #%v: missing value", i)
+		}
+
+		t := item.abiType()
+		if !abiTypesMatch(itemType, t) {
+			return nil, fmt.Errorf("array item #%v: mismatching types; expected: %v, given: %v", i, itemType, t)
+		}
+		itemType = mergeABITypes(itemType, t)
+
+		if buf, err = item.encodeABI(buf); err != nil {
+			return nil, fmt.Errorf("array item #%v: %v", i, err)
+		}
+	}
+
+	return buf, nil
+}
+
+// encodeInt encodes an integer, given as the bits of its two's complement representation,
+// in big-endian order, using as few bytes as possible.
+func encodeInt(buf []byte, marker byte, bits uint64) []byte {
+	n := 1
+	for n < 8 && bits>>(8*n) != 0 {
+		n++
+	}
+
+	buf = append(buf, marker|byte(n-1)<<4)
+	for i := n - 1; i >= 0; i-- {
+		buf = append(buf, byte(bits>>(8*i)))
+	}
+
+	return buf
+}
+
+func encodeArrayLen(buf []byte, length int) ([]byte, error) {
+	switch {
+	case length <= maxShortArrayLen:
+		return append(buf, byte(length)<<4|abiArray), nil
+	case length <= maxArrayLen:
+		return append(buf, abiArrayLong, byte(length)), nil
+	default:
+		return nil, fmt.Errorf("array is too long; max length: %v, given: %v", maxArrayLen, length)
+	}
+}
+
+// abiTypesMatch returns whether two ABI types may be items of the same array.
+// An empty type stands for the unknown item type of an empty array, and matches any type.
+func abiTypesMatch(a, b string) bool {
+	for strings.HasPrefix(a, "[") && strings.HasPrefix(b, "[") {
+		a, b = a[1:len(a)-1], b[1:len(b)-1]
+	}
+
+	return a == b || a == "" || b == ""
+}
+
+// mergeABITypes returns the most specific of two matching ABI types.
+func mergeABITypes(a, b string) string {
+	if len(b) > len(a) {
+		return b
+	}
+	return a
+}
+
+// EncodeCallDataValues encodes `values` as calldata, natively, without going through the codec wasm.
+// Unlike `EncodeCallData`, it supports all of the ABI types; see `ABIValue`.
+func EncodeCallDataValues(values ...ABIValue) ([]byte, error) {
+	calldata := []byte{}
+	for i, v := range values {
+		if v == nil {
+			return nil, fmt.Errorf("invalid calldata value #%v: missing value", i)
+		}
+
+		var err error
+		if calldata, err = v.encodeABI(calldata); err != nil {
+			return nil, fmt.Errorf("invalid calldata value #%v: %v", i, err)
+		}
+	}
+
+	return calldata, nil
+}
+
+// maxArrayDepth is the maximum nesting depth of decoded arrays.
+const maxArrayDepth = 32
+
+// abiDecoder decodes ABI values natively, mirroring `svm-abi-decoder`.
+type abiDecoder struct {
+	data []byte
+
+	// pos is the offset of the next byte to read.
+	pos int
+}
+
+func (d *abiDecoder) done() bool {
+	return d.pos == len(d.data)
+}
+
+func (d *abiDecoder) bytes(n int) ([]byte, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errUnexpectedEOF
+	}
+
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *abiDecoder) value(depth int) (ABIValue, error) {
+	b, err := d.bytes(1)
+	if err != nil {
+		return nil, err
+	}
+
+	marker := b[0]
+	switch marker {
+	case abiBoolFalse:
+		return Bool(false), nil
+	case abiBoolTrue:
+		return Bool(true), nil
+	case abiAddress:
+		b, err := d.bytes(common.AddressSize)
+		if err != nil {
+			return nil, err
+		}
+		return Address(common.BytesToAddress(b)), nil
+	case abiArrayLong:
+		b, err := d.bytes(1)
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(b[0]), depth)
+	}
+
+	// The high nibble of the remaining markers holds either an array's length,
+	// or the number of bytes of an integer, minus one.
+	hi := int(marker >> 4)
+	switch marker & 0x0f {
+	case abiArray:
+		return d.array(hi, depth)
+	case abiAmount:
+		bits, err := d.int(hi+1, 8)
+		return Amount(bits), err
+	case abiI8:
+		switch {
+		case hi == 0:
+			bits, err := d.int(1, 1)
+			return I8(bits), err
+		case hi == 1:
+			bits, err := d.int(1, 1)
+			return U8(bits), err
+		case hi < 4:
+			bits, err := d.int(hi-1, 2)
+			return I16(bits), err
+		case hi < 6:
+			bits, err := d.int(hi-3, 2)
+			return U16(bits), err
+		}
+	case abiI32:
+		if hi < 4 {
+			bits, err := d.int(hi+1, 4)
+			return I32(bits), err
+		}
+		bits, err := d.int(hi-3, 4)
+		return U32(bits), err
+	case abiI64:
+		bits, err := d.int(hi+1, 8)
+		return I64(bits), err
+	case abiU64:
+		bits, err := d.int(hi+1, 8)
+		return U64(bits), err
+	}
+
+	return nil, fmt.Errorf("invalid marker: 0x%02x", marker)
+}
+
+// int decodes the `n` big-endian bytes of an integer, which may take up to `max` bytes.
+func (d *abiDecoder) int(n int, max int) (uint64, error) {
+	if n > max {
+		return 0, fmt.Errorf("invalid integer size: %v bytes", n)
+	}
+
+	b, err := d.bytes(n)
+	if err != nil {
+		return 0, err
+	}
+
+	var bits uint64
+	for _, v := range b {
+		bits = bits<<8 | uint64(v)
+	}
+
+	return bits, nil
+}
+
+func (d *abiDecoder) array(length int, depth int) (ABIValue, error) {
+	if depth == maxArrayDepth {
+		return nil, fmt.Errorf("arrays are nested too deep; max depth: %v", maxArrayDepth)
+	}
+
+	arr := make(Array, length)
+	for i := range arr {
+		item, err := d.value(depth + 1)
+		if err != nil {
+			return nil, fmt.Errorf("array item #%v: %v", i, err)
+		}
+		arr[i] = item
+	}
+
+	return arr, nil
+}
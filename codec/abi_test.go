@@ -0,0 +1,190 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestEncodeCallDataValues(t *testing.T) {
+	cases := []struct {
+		values []ABIValue
+		hex    string
+	}{
+		// As encoded by `wasm_encode_calldata`.
+		{[]ABIValue{U32(10)}, "430a"},
+		{[]ABIValue{U32(0)}, "4300"},
+		{[]ABIValue{U32(65536)}, "63010000"},
+		{[]ABIValue{Bool(true), Bool(false)}, "1000"},
+		{[]ABIValue{I8(-1), U8(255)}, "02ff12ff"},
+		{[]ABIValue{I16(-2), I16(200), U16(300), U16(5)}, "32fffe22c852012c4205"},
+		{[]ABIValue{I32(-5), I32(200)}, "33fffffffb03c8"},
+		{[]ABIValue{I64(-1), I64(128), U64(1099511627776), U64(0)}, "74ffffffffffffffff" + "0480" + "55010000000000" + "0500"},
+		{[]ABIValue{Amount(5)}, "0105"},
+		{[]ABIValue{Address(txAddr)}, "40bc213ffe5f285adf9b2df9975a98a8f3b8106bf7"},
+		{[]ABIValue{Array{U32(1), U32(2)}}, "2643014302"},
+		{[]ABIValue{Array{}}, "06"},
+		{[]ABIValue{Bytes{1, 2, 3}}, "36120112021203"},
+		{[]ABIValue{Array{Bytes{1}, Bytes{2, 3}}}, "261612012612021203"},
+		{[]ABIValue{Array{Array{}, Array{U8(1)}}}, "2606161201"},
+
+		// Arrays longer than 6 items are followed by their length, which the codec wasm fails to encode.
+		{[]ABIValue{Bytes{0, 1, 2, 3, 4, 5, 6}}, "7607" + "1200120112021203120412051206"},
+		{[]ABIValue{}, ""},
+	}
+
+	for _, c := range cases {
+		calldata, err := EncodeCallDataValues(c.values...)
+		require.NoError(t, err, "%v", c.values)
+		require.Equal(t, c.hex, fmt.Sprintf("%x", calldata), "%v", c.values)
+	}
+}
+
+func TestEncodeCallDataValues_Invalid(t *testing.T) {
+	cases := []struct {
+		values []ABIValue
+		err    string
+	}{
+		{[]ABIValue{U32(1), nil}, "invalid calldata value #1: missing value"},
+		{[]ABIValue{Array{U32(1), nil}}, "invalid calldata value #0: array item #1: missing value"},
+		{[]ABIValue{Array{U32(1), U16(2)}},
+			"invalid calldata value #0: array item #1: mismatching types; expected: u32, given: u16"},
+		{[]ABIValue{Array{Bytes{1}, Array{U16(2)}}},
+			"invalid calldata value #0: array item #1: mismatching types; expected: [u8], given: [u16]"},
+		{[]ABIValue{Array{Array{}, Array{U8(1)}, Array{I8(1)}}},
+			"invalid calldata value #0: array item #2: mismatching types; expected: [u8], given: [i8]"},
+		{[]ABIValue{Bytes(make([]byte, 256))},
+			"invalid calldata value #0: array is too long; max length: 255, given: 256"},
+	}
+
+	for _, c := range cases {
+		_, err := EncodeCallDataValues(c.values...)
+		require.EqualError(t, err, c.err, "%v", c.values)
+	}
+}
+
+// abiGenerator generates random ABI values, along with their representation by the codec wasm's JSON API.
+type abiGenerator struct {
+	rnd *rand.Rand
+}
+
+func (g abiGenerator) value(depth int) (ABIValue, interface{}) {
+	kinds := 13
+	if depth == 0 {
+		kinds = 11
+	}
+
+	switch g.rnd.Intn(kinds) {
+	case 0:
+		v := g.rnd.Intn(2) == 1
+		return Bool(v), v
+	case 1:
+		v := int8(g.rnd.Uint64())
+		return I8(v), json.Number(fmt.Sprint(v))
+	case 2:
+		v := int16(g.int(16))
+		return I16(v), json.Number(fmt.Sprint(v))
+	case 3:
+		v := int32(g.int(32))
+		return I32(v), json.Number(fmt.Sprint(v))
+	case 4:
+		v := int64(g.int(64))
+		return I64(v), json.Number(fmt.Sprint(v))
+	case 5:
+		v := uint8(g.rnd.Uint64())
+		return U8(v), json.Number(fmt.Sprint(v))
+	case 6:
+		v := uint16(g.int(16))
+		return U16(v), json.Number(fmt.Sprint(v))
+	case 7:
+		v := uint32(g.int(32))
+		return U32(v), json.Number(fmt.Sprint(v))
+	case 8:
+		v := g.int(64)
+		return U64(v), json.Number(fmt.Sprint(v))
+	case 9:
+		v := g.int(64)
+		return Amount(v), json.Number(fmt.Sprint(v))
+	case 10:
+		v := receiptGenerator{g.rnd}.address()
+		return Address(v), strings.ToUpper(v.String())
+	case 11:
+		v := make(Bytes, g.length())
+		g.rnd.Read(v)
+		items := make([]interface{}, len(v))
+		for i, b := range v {
+			items[i] = json.Number(fmt.Sprint(b))
+		}
+		return v, items
+	default:
+		// All items share the type of the first one.
+		first, _ := g.value(depth - 1)
+		itemType := first.abiType()
+		arr := make(Array, g.length())
+		items := make([]interface{}, len(arr))
+		for i := range arr {
+			for {
+				arr[i], items[i] = g.value(depth - 1)
+				if abiTypesMatch(itemType, arr[i].abiType()) {
+					itemType = mergeABITypes(itemType, arr[i].abiType())
+					break
+				}
+			}
+		}
+		return arr, items
+	}
+}
+
+// int returns a random integer of up to `bits` bits, biased towards short encodings.
+func (g abiGenerator) int(bits int) uint64 {
+	return g.rnd.Uint64() >> uint(64-1-g.rnd.Intn(bits))
+}
+
+func (g abiGenerator) length() int {
+	if g.rnd.Intn(4) == 0 {
+		return g.rnd.Intn(maxArrayLen + 1)
+	}
+	return g.rnd.Intn(10)
+}
+
+// TestEncodeCallDataValues_Differential verifies that calldata encoded natively is decoded as expected
+// by the codec wasm, which shares its ABI decoder with `svm_sdk::CallData`.
+func TestEncodeCallDataValues_Differential(t *testing.T) {
+	g := abiGenerator{rnd: rand.New(rand.NewSource(1))}
+
+	for i := 0; i < 500; i++ {
+		values := make([]ABIValue, 1+g.rnd.Intn(4))
+		expected := make([]interface{}, len(values))
+		for j := range values {
+			values[j], expected[j] = g.value(2)
+		}
+
+		calldata, err := EncodeCallDataValues(values...)
+		require.NoError(t, err)
+
+		decoded, err := DecodeReturndata(calldata)
+		require.NoError(t, err, "%x", calldata)
+
+		var actual struct {
+			Data []interface{} `json:"data"`
+		}
+		dec := json.NewDecoder(bytes.NewReader([]byte(decoded)))
+		dec.UseNumber()
+		require.NoError(t, dec.Decode(&actual))
+		require.Equal(t, expected, actual.Data, "%x", calldata)
+	}
+}
+
+func BenchmarkEncodeCallDataValues(b *testing.B) {
+	values := []ABIValue{U32(10), Address(txAddr), Array{U64(1), U64(1 << 40)}, Bytes(make([]byte, 32))}
+
+	for i := 0; i < b.N; i++ {
+		if _, err := EncodeCallDataValues(values...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
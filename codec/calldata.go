@@ -0,0 +1,257 @@
+package codec
+
+import (
+	"fmt"
+	"go-svm/common"
+	"reflect"
+	"strings"
+)
+
+// callDataTag is the struct tag which holds a field's ABI type, e.g., `svm:"u32"`.
+const callDataTag = "svm"
+
+// abiInts holds the ABI integer types, by name.
+var abiInts = map[string]struct {
+	signed bool
+	bits   uint
+}{
+	"i8":     {true, 8},
+	"i16":    {true, 16},
+	"i32":    {true, 32},
+	"i64":    {true, 64},
+	"u8":     {false, 8},
+	"u16":    {false, 16},
+	"u32":    {false, 32},
+	"u64":    {false, 64},
+	"amount": {false, 64},
+}
+
+// callDataField is a struct field which is encoded as a calldata value.
+type callDataField struct {
+	index   int
+	name    string
+	abiType string
+}
+
+// callDataFields returns the fields of a struct which are encoded as calldata, in order.
+//
+// Each exported field must have an `svm` tag, holding its ABI type: `bool`, `i8`–`i64`, `u8`–`u64`,
+// `amount`, `address`, or an array of any of these, e.g., `[u8]` or `[[u32]]`.
+// Fields tagged with `svm:"-"` are skipped, as are unexported fields.
+func callDataFields(t reflect.Type) ([]callDataField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected: a struct, given: %v", t)
+	}
+
+	var fields []callDataField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		abiType, ok := field.Tag.Lookup(callDataTag)
+		switch {
+		case abiType == "-":
+			continue
+		case !ok:
+			return nil, fmt.Errorf("invalid `%v` field; missing `%v` tag", field.Name, callDataTag)
+		}
+
+		if err := checkABIType(abiType, field.Type); err != nil {
+			return nil, fmt.Errorf("invalid `%v` field; %v", field.Name, err)
+		}
+
+		fields = append(fields, callDataField{index: i, name: field.Name, abiType: abiType})
+	}
+
+	return fields, nil
+}
+
+// checkABIType returns an error unless values of type `t` can be encoded as `abiType`.
+func checkABIType(abiType string, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if itemType, ok := abiArrayItemType(abiType); ok {
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return fmt.Errorf("cannot use %v as %v", t, abiType)
+		}
+		if err := checkABIType(itemType, t.Elem()); err != nil {
+			return fmt.Errorf("array item: %v", err)
+		}
+		return nil
+	}
+
+	ok := false
+	switch _, isInt := abiInts[abiType]; {
+	case isInt:
+		ok = isIntKind(t.Kind()) || isUintKind(t.Kind())
+	case abiType == "bool":
+		ok = t.Kind() == reflect.Bool
+	case abiType == "address":
+		ok = t.ConvertibleTo(addressType) && t.Kind() == reflect.Array
+	default:
+		return fmt.Errorf("unsupported ABI type: `%v`", abiType)
+	}
+	if !ok {
+		return fmt.Errorf("cannot use %v as %v", t, abiType)
+	}
+
+	return nil
+}
+
+// abiArrayItemType returns the item type of an ABI array type, e.g., `u32` for `[u32]`.
+func abiArrayItemType(abiType string) (string, bool) {
+	if len(abiType) < 2 || !strings.HasPrefix(abiType, "[") || !strings.HasSuffix(abiType, "]") {
+		return "", false
+	}
+	return abiType[1 : len(abiType)-1], true
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+// toABIValue converts a Go value to an ABI value of type `abiType`, which `checkABIType` accepts.
+func toABIValue(abiType string, v reflect.Value) (ABIValue, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fmt.Errorf("missing value")
+		}
+		v = v.Elem()
+	}
+
+	if itemType, ok := abiArrayItemType(abiType); ok {
+		arr := make(Array, v.Len())
+		for i := range arr {
+			item, err := toABIValue(itemType, v.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("item #%v: %v", i, err)
+			}
+			arr[i] = item
+		}
+		return arr, nil
+	}
+
+	switch abiType {
+	case "bool":
+		return Bool(v.Bool()), nil
+	case "address":
+		return Address(v.Convert(addressType).Interface().(common.Address)), nil
+	}
+
+	// Shifts by 64 bits or more yield zero, so the range checks hold for 64-bit types too.
+	typ := abiInts[abiType]
+	var bits uint64
+	if isIntKind(v.Kind()) {
+		i := v.Int()
+		if typ.signed && i<<(64-typ.bits)>>(64-typ.bits) != i || !typ.signed && (i < 0 || uint64(i)>>typ.bits != 0) {
+			return nil, fmt.Errorf("value %v overflows %v", i, abiType)
+		}
+		bits = uint64(i)
+	} else {
+		u := v.Uint()
+		if typ.signed && u>>(typ.bits-1) != 0 || !typ.signed && u>>typ.bits != 0 {
+			return nil, fmt.Errorf("value %v overflows %v", u, abiType)
+		}
+		bits = u
+	}
+
+	switch abiType {
+	case "i8":
+		return I8(bits), nil
+	case "i16":
+		return I16(bits), nil
+	case "i32":
+		return I32(bits), nil
+	case "i64":
+		return I64(bits), nil
+	case "u8":
+		return U8(bits), nil
+	case "u16":
+		return U16(bits), nil
+	case "u32":
+		return U32(bits), nil
+	case "u64":
+		return U64(bits), nil
+	default:
+		return Amount(bits), nil
+	}
+}
+
+// MarshalCallData encodes a struct, or a pointer to one, as calldata.
+// The struct's fields are encoded in order, according to the ABI types in their `svm` tags:
+//
+//	type counterAddArgs struct {
+//		Amount uint32         `svm:"u32"`
+//		Owner  common.Address `svm:"address"`
+//		Memo   []byte         `svm:"[u8]"`
+//	}
+//
+// Integers must fit their ABI type, and addresses may be of any type convertible to `common.Address`.
+func MarshalCallData(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("invalid calldata; expected: a struct, given: %T", v)
+	}
+
+	fields, err := callDataFields(rv.Type())
+	if err != nil {
+		return nil, fmt.Errorf("invalid calldata: %v", err)
+	}
+
+	values := make([]ABIValue, len(fields))
+	for i, field := range fields {
+		if values[i], err = toABIValue(field.abiType, rv.Field(field.index)); err != nil {
+			return nil, fmt.Errorf("invalid calldata: invalid `%v` field; %v", field.name, err)
+		}
+	}
+
+	return EncodeCallDataValues(values...)
+}
+
+// UnmarshalCallData decodes calldata, or returndata, into the struct pointed to by `v`,
+// whose fields are tagged as described by `MarshalCallData`.
+// The data must hold a value for each of the tagged fields, of the field's ABI type.
+func UnmarshalCallData(b []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("invalid output; expected: a non-nil pointer to a struct, given: %T", v)
+	}
+	rv = rv.Elem()
+
+	fields, err := callDataFields(rv.Type())
+	if err != nil {
+		return fmt.Errorf("invalid output: %v", err)
+	}
+
+	values, err := DecodeReturndataValues(b)
+	if err != nil {
+		return err
+	}
+	if len(values) != len(fields) {
+		return fmt.Errorf("invalid calldata; expected: %v values, given: %v", len(fields), len(values))
+	}
+
+	for i, field := range fields {
+		value := values[i]
+		if t := value.abiType(); !abiTypesMatch(field.abiType, t) {
+			return fmt.Errorf("invalid calldata: invalid `%v` field; expected: %v, given: %v", field.name, field.abiType, t)
+		}
+
+		if err := assignABIValue(rv.Field(field.index), value); err != nil {
+			return fmt.Errorf("invalid calldata: invalid `%v` field; %v", field.name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package codec
+
+import (
+	"github.com/stretchr/testify/require"
+	"go-svm/common"
+	"math"
+	"testing"
+)
+
+type transferArgs struct {
+	Ok       bool             `svm:"bool"`
+	Delta    int8             `svm:"i8"`
+	Offset   int              `svm:"i32"`
+	Balance  int64            `svm:"i64"`
+	Flags    uint16           `svm:"u16"`
+	Counter  uint32           `svm:"u32"`
+	Nonce    uint             `svm:"u64"`
+	Fee      uint64           `svm:"amount"`
+	Owner    common.Address   `svm:"address"`
+	Spender  *Address         `svm:"address"`
+	Memo     []byte           `svm:"[u8]"`
+	Hash     [4]byte          `svm:"[u8]"`
+	Matrix   [][]uint32       `svm:"[[u32]]"`
+	Peers    []common.Address `svm:"[address]"`
+	Internal string           `svm:"-"`
+	private  string
+}
+
+func TestMarshalCallData(t *testing.T) {
+	req := require.New(t)
+
+	spender := Address(txAddr)
+	args := transferArgs{
+		Ok:       true,
+		Delta:    -1,
+		Offset:   -5,
+		Balance:  math.MinInt64,
+		Flags:    300,
+		Counter:  10,
+		Nonce:    1 << 40,
+		Fee:      math.MaxUint64,
+		Owner:    txAddr,
+		Spender:  &spender,
+		Memo:     []byte{1, 2, 3},
+		Hash:     [4]byte{0xde, 0xad, 0xbe, 0xef},
+		Matrix:   [][]uint32{{}, {1, 2}},
+		Peers:    []common.Address{txAddr},
+		Internal: "skipped",
+		private:  "skipped",
+	}
+
+	calldata, err := MarshalCallData(args)
+	req.NoError(err)
+
+	expected, err := EncodeCallDataValues(
+		Bool(true),
+		I8(-1),
+		I32(-5),
+		I64(math.MinInt64),
+		U16(300),
+		U32(10),
+		U64(1<<40),
+		Amount(math.MaxUint64),
+		Address(txAddr),
+		Address(txAddr),
+		Bytes{1, 2, 3},
+		Bytes{0xde, 0xad, 0xbe, 0xef},
+		Array{Array{}, Array{U32(1), U32(2)}},
+		Array{Address(txAddr)},
+	)
+	req.NoError(err)
+	req.Equal(expected, calldata)
+
+	// A pointer is encoded as the struct it points to.
+	calldata, err = MarshalCallData(&args)
+	req.NoError(err)
+	req.Equal(expected, calldata)
+
+	var decoded transferArgs
+	req.NoError(UnmarshalCallData(calldata, &decoded))
+	args.Internal, args.private = "", ""
+	req.Equal(args, decoded)
+}
+
+func TestMarshalCallData_Counter(t *testing.T) {
+	req := require.New(t)
+
+	var args struct {
+		Amount uint32 `svm:"u32"`
+	}
+	args.Amount = 5
+
+	calldata, err := MarshalCallData(args)
+	req.NoError(err)
+
+	expected, err := EncodeCallDataValues(U32(5))
+	req.NoError(err)
+	req.Equal(expected, calldata)
+
+	// `counter_add` returns the counter's old and new values as a `Vec<u32>`.
+	returndata, err := EncodeCallDataValues(Array{U32(10), U32(15)})
+	req.NoError(err)
+
+	var result struct {
+		Values []uint32 `svm:"[u32]"`
+	}
+	req.NoError(UnmarshalCallData(returndata, &result))
+	req.Equal([]uint32{10, 15}, result.Values)
+}
+
+func TestMarshalCallData_Invalid(t *testing.T) {
+	cases := []struct {
+		v   interface{}
+		err string
+	}{
+		{nil, "invalid calldata; expected: a struct, given: <nil>"},
+		{5, "invalid calldata; expected: a struct, given: int"},
+		{(*transferArgs)(nil), "invalid calldata; expected: a struct, given: *codec.transferArgs"},
+		{struct{ A uint32 }{}, "invalid calldata: invalid `A` field; missing `svm` tag"},
+		{struct {
+			A string `svm:"string"`
+		}{}, "invalid calldata: invalid `A` field; unsupported ABI type: `string`"},
+		{struct {
+			A uint32 `svm:""`
+		}{}, "invalid calldata: invalid `A` field; unsupported ABI type: ``"},
+		{struct {
+			A []uint32 `svm:"[]"`
+		}{}, "invalid calldata: invalid `A` field; array item: unsupported ABI type: ``"},
+		{struct {
+			A string `svm:"u32"`
+		}{}, "invalid calldata: invalid `A` field; cannot use string as u32"},
+		{struct {
+			A []byte `svm:"address"`
+		}{}, "invalid calldata: invalid `A` field; cannot use []uint8 as address"},
+		{struct {
+			A uint32 `svm:"[u32]"`
+		}{}, "invalid calldata: invalid `A` field; cannot use uint32 as [u32]"},
+		{struct {
+			A []int `svm:"[bool]"`
+		}{}, "invalid calldata: invalid `A` field; array item: cannot use int as bool"},
+		{struct {
+			A int `svm:"u8"`
+		}{256}, "invalid calldata: invalid `A` field; value 256 overflows u8"},
+		{struct {
+			A int `svm:"u32"`
+		}{-1}, "invalid calldata: invalid `A` field; value -1 overflows u32"},
+		{struct {
+			A int64 `svm:"i16"`
+		}{-32769}, "invalid calldata: invalid `A` field; value -32769 overflows i16"},
+		{struct {
+			A uint64 `svm:"i64"`
+		}{1 << 63}, "invalid calldata: invalid `A` field; value 9223372036854775808 overflows i64"},
+		{struct {
+			A []uint16 `svm:"[u8]"`
+		}{[]uint16{1, 256}}, "invalid calldata: invalid `A` field; item #1: value 256 overflows u8"},
+		{struct {
+			A *uint32 `svm:"u32"`
+		}{}, "invalid calldata: invalid `A` field; missing value"},
+	}
+
+	for _, c := range cases {
+		_, err := MarshalCallData(c.v)
+		require.EqualError(t, err, c.err, "%#v", c.v)
+	}
+}
+
+func TestUnmarshalCallData_Invalid(t *testing.T) {
+	calldata, err := EncodeCallDataValues(U32(10), Array{U8(1)})
+	require.NoError(t, err)
+
+	var out struct {
+		A uint32  `svm:"u32"`
+		B []uint8 `svm:"[u8]"`
+	}
+	require.EqualError(t, UnmarshalCallData(calldata, transferArgs{}),
+		"invalid output; expected: a non-nil pointer to a struct, given: codec.transferArgs")
+	require.EqualError(t, UnmarshalCallData(calldata, new(int)), "invalid output; expected: a non-nil pointer to a struct, given: *int")
+	require.EqualError(t, UnmarshalCallData(calldata[:1], &out), "invalid returndata value #0: unexpected end of data")
+
+	var missingTag struct{ A uint32 }
+	require.EqualError(t, UnmarshalCallData(calldata, &missingTag), "invalid output: invalid `A` field; missing `svm` tag")
+
+	var tooFew struct {
+		A uint32 `svm:"u32"`
+	}
+	require.EqualError(t, UnmarshalCallData(calldata, &tooFew), "invalid calldata; expected: 1 values, given: 2")
+
+	var mismatch struct {
+		A uint16  `svm:"u16"`
+		B []uint8 `svm:"[u8]"`
+	}
+	require.EqualError(t, UnmarshalCallData(calldata, &mismatch), "invalid calldata: invalid `A` field; expected: u16, given: u32")
+
+	var arrayMismatch struct {
+		A uint32   `svm:"u32"`
+		B []uint16 `svm:"[u16]"`
+	}
+	require.EqualError(t, UnmarshalCallData(calldata, &arrayMismatch),
+		"invalid calldata: invalid `B` field; expected: [u16], given: [u8]")
+
+	var overflow struct {
+		A uint8   `svm:"u32"`
+		B []uint8 `svm:"[u8]"`
+	}
+	calldata, err = EncodeCallDataValues(U32(256), Array{})
+	require.NoError(t, err)
+	require.EqualError(t, UnmarshalCallData(calldata, &overflow), "invalid calldata: invalid `A` field; value 256 overflows uint8")
+}
@@ -7,62 +7,156 @@ import (
 	"fmt"
 	"github.com/wasmerio/wasmer-go/wasmer"
 	"go-svm/common"
-	"io/ioutil"
-	"path/filepath"
 	"runtime"
+	"sync"
 )
 
 type (
 	ReceiptDeployTemplate = common.ReceiptDeployTemplate
 	ReceiptSpawnApp       = common.ReceiptSpawnApp
 	ReceiptExecApp        = common.ReceiptExecApp
-)
 
-const (
-	markerErr = 0
-	markerOk  = 1
+	TxDeployTemplate = common.TxDeployTemplate
+	TxSpawnApp       = common.TxSpawnApp
+	TxExecApp        = common.TxExecApp
 )
 
+// Codec encodes transactions and calldata, and decodes returndata, using the codec wasm.
+//
+// A Codec is safe for concurrent use. It is backed by a bounded pool of wasm instances,
+// each used by a single call at a time. Calls block while all instances are in use.
+type Codec struct {
+	pool *instancePool
+}
+
+// Option configures a Codec.
+type Option func(*config)
+
+type config struct {
+	poolSize  int
+	wasmBytes []byte
+}
+
+// WithPoolSize sets the maximum number of wasm instances of the Codec.
+// It defaults to the number of CPUs.
+func WithPoolSize(size int) Option {
+	return func(c *config) {
+		c.poolSize = size
+	}
+}
+
+// WithWasmBytes sets the codec wasm module to use, instead of the one bundled into the package.
+func WithWasmBytes(b []byte) Option {
+	return func(c *config) {
+		c.wasmBytes = b
+	}
+}
+
 var (
-	instance *wasmer.Instance
+	defaultCodecOnce sync.Once
+	defaultCodecInst *Codec
+	defaultCodecErr  error
 )
 
-func init() {
-	var err error
-	instance, err = newInstance(codecWasmFilePath())
+// defaultCodec returns the Codec which backs the package-level functions.
+// It's created on first use, and a failure to create it is returned by every call.
+func defaultCodec() (*Codec, error) {
+	defaultCodecOnce.Do(func() {
+		defaultCodecInst, defaultCodecErr = New()
+		if defaultCodecErr != nil {
+			defaultCodecErr = fmt.Errorf("failed to create the default codec: %v", defaultCodecErr)
+		}
+	})
+
+	return defaultCodecInst, defaultCodecErr
+}
+
+// New creates a new Codec. The codec wasm is compiled once,
+// while its instances are created on demand.
+func New(opts ...Option) (*Codec, error) {
+	cfg := config{poolSize: runtime.NumCPU(), wasmBytes: bundledWasm}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.poolSize < 1 {
+		return nil, fmt.Errorf("invalid pool size: %v", cfg.poolSize)
+	}
+	if len(cfg.wasmBytes) == 0 {
+		return nil, errors.New("missing codec wasm")
+	}
+
+	engine := wasmer.NewEngine()
+	store := wasmer.NewStore(engine)
+
+	module, err := wasmer.NewModule(store, cfg.wasmBytes)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("invalid codec wasm: %v", err)
 	}
+
+	return &Codec{
+		pool: newInstancePool(cfg.poolSize, func() (*instance, error) {
+			return newInstance(module)
+		}),
+	}, nil
 }
 
-func codecWasmFilePath() string {
-	_, file, _, _ := runtime.Caller(0)
-	return filepath.Join(file, "../../svm/svm_codec.wasm")
+// call calls an exported codec function on an instance from the pool.
+func (c *Codec) call(name string, input []byte) ([]byte, error) {
+	inst, err := c.pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer c.pool.release(inst)
+
+	return inst.call(name, input)
 }
 
-func newInstance(filename string) (*wasmer.Instance, error) {
-	bytes, err := ioutil.ReadFile(filename)
+func EncodeTxDeployTemplate(version int, name string, code []byte, data []byte) ([]byte, error) {
+	c, err := defaultCodec()
 	if err != nil {
 		return nil, err
 	}
 
-	engine := wasmer.NewEngine()
-	store := wasmer.NewStore(engine)
+	return c.EncodeTxDeployTemplate(version, name, code, data)
+}
 
-	module, err := wasmer.NewModule(store, bytes)
+func EncodeTxSpawnApp(version int, templateAddr []byte, name string, ctorName string, calldata []byte) ([]byte, error) {
+	c, err := defaultCodec()
 	if err != nil {
 		return nil, err
 	}
 
-	instance, err := wasmer.NewInstance(module, wasmer.NewImportObject())
+	return c.EncodeTxSpawnApp(version, templateAddr, name, ctorName, calldata)
+}
+
+func EncodeTxExecApp(version int, appAddr []byte, funcName string, calldata []byte) ([]byte, error) {
+	c, err := defaultCodec()
 	if err != nil {
 		return nil, err
 	}
 
-	return instance, nil
+	return c.EncodeTxExecApp(version, appAddr, funcName, calldata)
 }
 
-func EncodeTxDeployTemplate(version int, name string, code []byte, data []byte) ([]byte, error) {
+func EncodeCallData(abi []string, data []int) ([]byte, error) {
+	c, err := defaultCodec()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.EncodeCallData(abi, data)
+}
+
+func DecodeReturndata(rawReturndata []byte) (string, error) {
+	c, err := defaultCodec()
+	if err != nil {
+		return "", err
+	}
+
+	return c.DecodeReturndata(rawReturndata)
+}
+
+func (c *Codec) EncodeTxDeployTemplate(version int, name string, code []byte, data []byte) ([]byte, error) {
 	txJson, err := json.Marshal(struct {
 		Version int    `json:"version"`
 		Name    string `json:"name"`
@@ -78,25 +172,10 @@ func EncodeTxDeployTemplate(version int, name string, code []byte, data []byte)
 		return nil, err
 	}
 
-	argPtr, err := newBuffer(txJson)
-	if err != nil {
-		return nil, err
-	}
-
-	fn, err := instance.Exports.GetFunction("wasm_deploy_template")
-	if err != nil {
-		return nil, err
-	}
-
-	retPtr, err := fn(argPtr)
-	if err != nil {
-		return nil, err
-	}
-
-	return loadBuffer(retPtr.(int32))
+	return c.call("wasm_deploy_template", txJson)
 }
 
-func EncodeTxSpawnApp(version int, templateAddr []byte, name string, ctorName string, calldata []byte) ([]byte, error) {
+func (c *Codec) EncodeTxSpawnApp(version int, templateAddr []byte, name string, ctorName string, calldata []byte) ([]byte, error) {
 	txJson, err := json.Marshal(struct {
 		Version      int    `json:"version"`
 		TemplateAddr string `json:"template"`
@@ -114,25 +193,10 @@ func EncodeTxSpawnApp(version int, templateAddr []byte, name string, ctorName st
 		return nil, err
 	}
 
-	argPtr, err := newBuffer(txJson)
-	if err != nil {
-		return nil, err
-	}
-
-	fn, err := instance.Exports.GetFunction("wasm_encode_spawn_app")
-	if err != nil {
-		return nil, err
-	}
-
-	retPtr, err := fn(argPtr)
-	if err != nil {
-		return nil, err
-	}
-
-	return loadBuffer(retPtr.(int32))
+	return c.call("wasm_encode_spawn_app", txJson)
 }
 
-func EncodeTxExecApp(version int, appAddr []byte, funcName string, calldata []byte) ([]byte, error) {
+func (c *Codec) EncodeTxExecApp(version int, appAddr []byte, funcName string, calldata []byte) ([]byte, error) {
 	txJson, err := json.Marshal(struct {
 		Version  int    `json:"version"`
 		AppAddr  string `json:"app"`
@@ -148,25 +212,10 @@ func EncodeTxExecApp(version int, appAddr []byte, funcName string, calldata []by
 		return nil, err
 	}
 
-	argPtr, err := newBuffer(txJson)
-	if err != nil {
-		return nil, err
-	}
-
-	fn, err := instance.Exports.GetFunction("wasm_encode_exec_app")
-	if err != nil {
-		return nil, err
-	}
-
-	retPtr, err := fn(argPtr)
-	if err != nil {
-		return nil, err
-	}
-
-	return loadBuffer(retPtr.(int32))
+	return c.call("wasm_encode_exec_app", txJson)
 }
 
-func EncodeCallData(abi []string, data []int) ([]byte, error) {
+func (c *Codec) EncodeCallData(abi []string, data []int) ([]byte, error) {
 	calldataJson, err := json.Marshal(struct {
 		ABI  []string `json:"abi"`
 		Data []int    `json:"data"`
@@ -178,22 +227,7 @@ func EncodeCallData(abi []string, data []int) ([]byte, error) {
 		return nil, err
 	}
 
-	argPtr, err := newBuffer(calldataJson)
-	if err != nil {
-		return nil, err
-	}
-
-	fn, err := instance.Exports.GetFunction("wasm_encode_calldata")
-	if err != nil {
-		return nil, err
-	}
-
-	retPtr, err := fn(argPtr)
-	if err != nil {
-		return nil, err
-	}
-
-	ret, err := loadBuffer(retPtr.(int32))
+	ret, err := c.call("wasm_encode_calldata", calldataJson)
 	if err != nil {
 		return nil, err
 	}
@@ -216,7 +250,7 @@ func EncodeCallData(abi []string, data []int) ([]byte, error) {
 	return bytes, nil
 }
 
-func DecodeReturndata(rawReturndata []byte) (string, error) {
+func (c *Codec) DecodeReturndata(rawReturndata []byte) (string, error) {
 	calldataJson, err := json.Marshal(struct {
 		Calldata string `json:"calldata"`
 	}{
@@ -227,22 +261,7 @@ func DecodeReturndata(rawReturndata []byte) (string, error) {
 		return "", err
 	}
 
-	argPtr, err := newBuffer(calldataJson)
-	if err != nil {
-		return "", err
-	}
-
-	fn, err := instance.Exports.GetFunction("wasm_decode_calldata")
-	if err != nil {
-		return "", err
-	}
-
-	retPtr, err := fn(argPtr)
-	if err != nil {
-		return "", err
-	}
-
-	ret, err := loadBuffer(retPtr.(int32))
+	ret, err := c.call("wasm_decode_calldata", calldataJson)
 	if err != nil {
 		return "", err
 	}
@@ -255,34 +274,55 @@ func DecodeReturndata(rawReturndata []byte) (string, error) {
 	return string(ret), nil
 }
 
+// DecodeReceiptDeployTemplate decodes a deploy-template receipt.
+// Receipts are decoded natively, so unlike the rest of the package, it doesn't involve the codec wasm.
 func DecodeReceiptDeployTemplate(rawReceipt []byte) (*ReceiptDeployTemplate, error) {
 	v, err := decodeReceipt(rawReceipt)
 	if err != nil {
 		return nil, err
 	}
 
-	return v.(*ReceiptDeployTemplate), nil
+	receipt, ok := v.(*ReceiptDeployTemplate)
+	if !ok {
+		return nil, fmt.Errorf("invalid receipt type; expected: deploy-template, given: %T", v)
+	}
+
+	return receipt, nil
 }
 
+// DecodeReceiptSpawnApp decodes a spawn-app receipt. See `DecodeReceiptDeployTemplate`.
 func DecodeReceiptSpawnApp(rawReceipt []byte) (*ReceiptSpawnApp, error) {
 	v, err := decodeReceipt(rawReceipt)
 	if err != nil {
 		return nil, err
 	}
 
-	return v.(*ReceiptSpawnApp), nil
+	receipt, ok := v.(*ReceiptSpawnApp)
+	if !ok {
+		return nil, fmt.Errorf("invalid receipt type; expected: spawn-app, given: %T", v)
+	}
+
+	return receipt, nil
 }
 
+// DecodeReceiptExecApp decodes an exec-app receipt. See `DecodeReceiptDeployTemplate`.
 func DecodeReceiptExecApp(rawReceipt []byte) (*ReceiptExecApp, error) {
 	v, err := decodeReceipt(rawReceipt)
 	if err != nil {
 		return nil, err
 	}
 
-	return v.(*ReceiptExecApp), nil
+	receipt, ok := v.(*ReceiptExecApp)
+	if !ok {
+		return nil, fmt.Errorf("invalid receipt type; expected: exec-app, given: %T", v)
+	}
+
+	return receipt, nil
 }
 
-func decodeReceipt(rawReceipt []byte) (interface{}, error) {
+// decodeReceiptWasm decodes a raw receipt using the codec wasm.
+// It's superseded by the native `decodeReceipt`, and is kept as its reference implementation.
+func (c *Codec) decodeReceiptWasm(rawReceipt []byte) (interface{}, error) {
 	decodeReceiptJson, err := json.Marshal(struct {
 		Data string `json:"data"`
 	}{
@@ -292,238 +332,154 @@ func decodeReceipt(rawReceipt []byte) (interface{}, error) {
 		return nil, err
 	}
 
-	bufPtr, err := newBuffer(decodeReceiptJson)
-	if err != nil {
-		return nil, err
-	}
-
-	fn, err := instance.Exports.GetFunction("wasm_decode_receipt")
-	if err != nil {
-		return nil, err
-	}
-
-	retBufPtr, err := fn(bufPtr)
-	if err != nil {
-		return nil, err
-	}
-
-	ret, err := loadBuffer(retBufPtr.(int32))
+	ret, err := c.call("wasm_decode_receipt", decodeReceiptJson)
 	if err != nil {
 		return nil, err
 	}
 
 	return decodeReceiptJSON(ret)
-
 }
 
 func decodeReceiptJSON(jsonReceipt []byte) (interface{}, error) {
-	var v map[string]interface{}
+	var v jsonObject
 	if err := json.Unmarshal(jsonReceipt, &v); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid receipt: %v", err)
 	}
-
-	errType, ok := v["err_type"].(string)
-	if ok {
-		switch errType {
-		case "oog":
-			return nil, errors.New("oog")
-		case "template-not-found":
-			templateAddr := v["template_addr"].(string)
-			return nil, fmt.Errorf("template not found; template address: %v", templateAddr)
-		case "app-not-found":
-			appAddr := v["app_addr"].(string)
-			return nil, fmt.Errorf("template not found; app address: %v", appAddr)
-		case "compilation-failed":
-			templateAddr := v["template_addr"].(string)
-			appAddr := v["app_addr"].(string)
-			msg := v["message"].(string)
-			return nil, fmt.Errorf("compilation failed; template address: %v, app address: %v, msg: %v",
-				templateAddr, appAddr, msg)
-		case "instantiation-failed":
-			templateAddr := v["template_addr"].(string)
-			appAddr := v["app_addr"].(string)
-			msg := v["message"].(string)
-			return nil, fmt.Errorf("instantiation failed; template address: %v, app address: %v, msg: %v",
-				templateAddr, appAddr, msg)
-		case "function-not-found":
-			templateAddr := v["template_addr"].(string)
-			appAddr := v["app_addr"].(string)
-			fnc := v["func"].(string)
-			return nil, fmt.Errorf("function not found; template address: %v, app address: %v, func: %v",
-				templateAddr, appAddr, fnc)
-		case "function-failed":
-			templateAddr := v["template_addr"].(string)
-			appAddr := v["app_addr"].(string)
-			fnc := v["func"].(string)
-			msg := v["message"].(string)
-			return nil, fmt.Errorf("function failed; template address: %v, app address: %v, func: %v, msg: %v",
-				templateAddr, appAddr, fnc, msg)
-		default:
-			panic(fmt.Sprintf("invalid error type: %v", errType))
-		}
-	} else {
-		ty := v["type"].(string)
-		switch ty {
-		case "deploy-template":
-			success := v["success"].(bool)
-			gasUsed := v["gas_used"].(float64)
-			addr := v["addr"].(string)
-
-			return &common.ReceiptDeployTemplate{
-				Success:      success,
-				TemplateAddr: common.BytesToAddress(mustDecodeHexString(addr)),
-				GasUsed:      uint64(gasUsed),
-			}, nil
-
-		case "spawn-app":
-			success := v["success"].(bool)
-			app := v["app"].(string)
-			state := v["state"].(string)
-			returndata := v["returndata"].(string)
-			logs := v["logs"].([]interface{})
-			gasUsed := v["gas_used"].(float64)
-
-			strLogs := make([]string, len(logs))
-			for i, log := range logs {
-				log := log.(map[string]interface{})
-				code := log["code"].(float64)
-				msg := log["msg"].(string)
-				strLogs[i] = fmt.Sprintf("(code: %v, msg: %v)", code, msg)
-			}
-
-			return &common.ReceiptSpawnApp{
-				Success:    success,
-				AppAddr:    common.BytesToAddress(mustDecodeHexString(app)),
-				State:      mustDecodeHexString(state),
-				Returndata: mustDecodeHexString(returndata),
-				Logs:       strLogs,
-				GasUsed:    uint64(gasUsed),
-			}, nil
-
-		case "exec-app":
-			success := v["success"].(bool)
-			newState := v["new_state"].(string)
-			returndata := v["returndata"].(string)
-			logs := v["logs"].([]interface{})
-			gasUsed := v["gas_used"].(float64)
-
-			strLogs := make([]string, len(logs))
-			for i, log := range logs {
-				log := log.(map[string]interface{})
-				code := log["code"].(float64)
-				msg := log["msg"].(string)
-				strLogs[i] = fmt.Sprintf("(code: %v, msg: %v)", code, msg)
-			}
-
-			return &common.ReceiptExecApp{
-				Success:    success,
-				NewState:   mustDecodeHexString(newState),
-				Returndata: mustDecodeHexString(returndata),
-				Logs:       strLogs,
-				GasUsed:    uint64(gasUsed),
-			}, nil
-
-		default:
-			panic(fmt.Sprintf("invalid receipt type: %v", ty))
-		}
+	if v == nil {
+		return nil, errors.New("invalid receipt: not a json object")
 	}
-}
 
-func newBuffer(data []byte) (int32, error) {
-	length := int32(len(data))
-	ptr, err := bufferAlloc(length)
-	if err != nil {
-		return 0, err
+	if _, ok := v["err_type"]; ok {
+		return nil, decodeReceiptErrorJSON(v)
 	}
 
-	bufferLength, err := bufferLength(ptr)
+	ty, err := v.string("type")
 	if err != nil {
-		return 0, err
-	}
-	if length != bufferLength {
-		panic(fmt.Sprintf("allocated buffer size isn't sufficient; allocated: %v, got: %v", length, bufferLength))
+		return nil, fmt.Errorf("invalid receipt: %v", err)
 	}
 
-	dataPtr, err := bufferDataPtr(ptr)
-	if err != nil {
-		return 0, err
+	var receipt interface{}
+	switch ty {
+	case "deploy-template":
+		receipt, err = decodeReceiptDeployTemplateJSON(v)
+	case "spawn-app":
+		receipt, err = decodeReceiptSpawnAppJSON(v)
+	case "exec-app":
+		receipt, err = decodeReceiptExecAppJSON(v)
+	default:
+		return nil, fmt.Errorf("invalid receipt: invalid receipt type: %v", ty)
 	}
-
-	mem, err := instance.Exports.GetMemory("memory")
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("invalid %v receipt: %v", ty, err)
 	}
 
-	copy(mem.Data()[dataPtr:], data)
-
-	return ptr, nil
+	return receipt, nil
 }
 
-func loadBuffer(ptr int32) ([]byte, error) {
-	length, err := bufferLength(ptr)
-	if err != nil {
-		return nil, err
-	}
-
-	dataPtr, err := bufferDataPtr(ptr)
-	if err != nil {
-		return nil, err
-	}
-
-	mem, err := instance.Exports.GetMemory("memory")
-	if err != nil {
-		return nil, err
-	}
-
-	buf := mem.Data()[dataPtr : dataPtr+length]
-	marker := buf[0]
-	data := buf[1:]
-
-	switch marker {
-	case markerErr:
-		return nil, errors.New(string(data))
-	case markerOk:
-		return data, nil
+func decodeReceiptErrorJSON(v jsonObject) error {
+	errType, err := v.string("err_type")
+	if err != nil {
+		return fmt.Errorf("invalid receipt: %v", err)
+	}
+
+	var receiptErr error
+	switch errType {
+	case "oog":
+		return common.ErrOutOfGas
+	case "template-not-found":
+		e := &common.TemplateNotFoundError{}
+		e.Addr, err = v.address("template_addr")
+		receiptErr = e
+	case "app-not-found":
+		e := &common.AppNotFoundError{}
+		e.Addr, err = v.address("app_addr")
+		receiptErr = e
+	case "compilation-failed":
+		e := &common.CompilationFailedError{}
+		err = v.decode(
+			v.addressField("template_addr", &e.TemplateAddr),
+			v.addressField("app_addr", &e.AppAddr),
+			v.stringField("message", &e.Msg),
+		)
+		receiptErr = e
+	case "instantiation-failed":
+		e := &common.InstantiationFailedError{}
+		err = v.decode(
+			v.addressField("template_addr", &e.TemplateAddr),
+			v.addressField("app_addr", &e.AppAddr),
+			v.stringField("message", &e.Msg),
+		)
+		receiptErr = e
+	case "function-not-found":
+		e := &common.FunctionNotFoundError{}
+		err = v.decode(
+			v.addressField("template_addr", &e.TemplateAddr),
+			v.addressField("app_addr", &e.AppAddr),
+			v.stringField("func", &e.Func),
+		)
+		receiptErr = e
+	case "function-failed":
+		e := &common.FunctionFailedError{}
+		err = v.decode(
+			v.addressField("template_addr", &e.TemplateAddr),
+			v.addressField("app_addr", &e.AppAddr),
+			v.stringField("func", &e.Func),
+			v.stringField("message", &e.Msg),
+		)
+		receiptErr = e
 	default:
-		panic("invalid marker")
+		return fmt.Errorf("invalid receipt: invalid error type: %v", errType)
 	}
-}
-
-func bufferAlloc(size int32) (int32, error) {
-	fn, err := instance.Exports.GetFunction("wasm_alloc")
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("invalid %v error receipt: %v", errType, err)
 	}
 
-	buf, err := fn(size)
-	return buf.(int32), err
+	return receiptErr
 }
 
-func bufferLength(buf int32) (int32, error) {
-	fn, err := instance.Exports.GetFunction("wasm_buffer_length")
+func decodeReceiptDeployTemplateJSON(v jsonObject) (*common.ReceiptDeployTemplate, error) {
+	r := &common.ReceiptDeployTemplate{}
+	err := v.decode(
+		v.boolField("success", &r.Success),
+		v.addressField("addr", &r.TemplateAddr),
+		v.uint64Field("gas_used", &r.GasUsed),
+	)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	bufLen, err := fn(buf)
-	return bufLen.(int32), err
+	return r, nil
 }
 
-func bufferDataPtr(buf int32) (int32, error) {
-	fn, err := instance.Exports.GetFunction("wasm_buffer_data")
+func decodeReceiptSpawnAppJSON(v jsonObject) (*common.ReceiptSpawnApp, error) {
+	r := &common.ReceiptSpawnApp{}
+	err := v.decode(
+		v.boolField("success", &r.Success),
+		v.addressField("app", &r.AppAddr),
+		v.hexField("state", &r.State),
+		v.hexField("returndata", &r.Returndata),
+		v.logsField("logs", &r.Logs),
+		v.uint64Field("gas_used", &r.GasUsed),
+	)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	dataPtr, err := fn(buf)
-	return dataPtr.(int32), err
+	return r, nil
 }
 
-func mustDecodeHexString(s string) []byte {
-	b, err := hex.DecodeString(s)
+func decodeReceiptExecAppJSON(v jsonObject) (*common.ReceiptExecApp, error) {
+	r := &common.ReceiptExecApp{}
+	err := v.decode(
+		v.boolField("success", &r.Success),
+		v.hexField("new_state", &r.NewState),
+		v.hexField("returndata", &r.Returndata),
+		v.logsField("logs", &r.Logs),
+		v.uint64Field("gas_used", &r.GasUsed),
+	)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return b
+	return r, nil
 }
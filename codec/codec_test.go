@@ -2,7 +2,15 @@ package codec
 
 import (
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/require"
+	"go-svm/common"
+	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
 	"testing"
 )
 
@@ -21,3 +29,370 @@ func TestCodec_DecodeDeployTemplateReceipt(t *testing.T) {
 	require.NotNil(t, receipt)
 	require.Equal(t, "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7", receipt.TemplateAddr.String())
 }
+
+func TestCodec_DecodeReceiptErrors(t *testing.T) {
+	req := require.New(t)
+
+	templateAddr := "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7"
+	appAddr := "a02fda0000000000000000000000000000000001"
+
+	_, err := decodeReceiptJSON([]byte(`{"err_type":"oog"}`))
+	req.True(errors.Is(err, common.ErrOutOfGas))
+
+	_, err = decodeReceiptJSON([]byte(`{"err_type":"template-not-found","template_addr":"` + templateAddr + `"}`))
+	var templateNotFound *common.TemplateNotFoundError
+	req.True(errors.As(err, &templateNotFound))
+	req.Equal(templateAddr, templateNotFound.Addr.String())
+
+	_, err = decodeReceiptJSON([]byte(`{"err_type":"app-not-found","app_addr":"` + appAddr + `"}`))
+	var appNotFound *common.AppNotFoundError
+	req.True(errors.As(err, &appNotFound))
+	req.Equal(appAddr, appNotFound.Addr.String())
+	req.EqualError(err, "app not found; app address: "+appAddr)
+
+	_, err = decodeReceiptJSON([]byte(`{"err_type":"compilation-failed","template_addr":"` + templateAddr +
+		`","app_addr":"` + appAddr + `","message":"Mayday"}`))
+	var compilationFailed *common.CompilationFailedError
+	req.True(errors.As(err, &compilationFailed))
+	req.Equal("Mayday", compilationFailed.Msg)
+
+	_, err = decodeReceiptJSON([]byte(`{"err_type":"instantiation-failed","template_addr":"` + templateAddr +
+		`","app_addr":"` + appAddr + `","message":"Mayday"}`))
+	var instantiationFailed *common.InstantiationFailedError
+	req.True(errors.As(err, &instantiationFailed))
+	req.Equal("Mayday", instantiationFailed.Msg)
+
+	_, err = decodeReceiptJSON([]byte(`{"err_type":"function-not-found","template_addr":"` + templateAddr +
+		`","app_addr":"` + appAddr + `","func":"run"}`))
+	var functionNotFound *common.FunctionNotFoundError
+	req.True(errors.As(err, &functionNotFound))
+	req.Equal("run", functionNotFound.Func)
+
+	_, err = decodeReceiptJSON([]byte(`{"err_type":"function-failed","template_addr":"` + templateAddr +
+		`","app_addr":"` + appAddr + `","func":"run","message":"Mayday"}`))
+	var functionFailed *common.FunctionFailedError
+	req.True(errors.As(err, &functionFailed))
+	req.Equal(templateAddr, functionFailed.TemplateAddr.String())
+	req.Equal(appAddr, functionFailed.AppAddr.String())
+	req.Equal("run", functionFailed.Func)
+	req.Equal("Mayday", functionFailed.Msg)
+}
+
+var validReceiptsJSON = []string{
+	`{"type":"deploy-template","success":true,"addr":"BC213FFE5F285ADF9B2DF9975A98A8F3B8106BF7","gas_used":196000,"logs":[]}`,
+	`{"type":"spawn-app","success":true,"app":"BC213FFE5F285ADF9B2DF9975A98A8F3B8106BF7","state":"00ff","returndata":"0102",` +
+		`"logs":[{"code":100,"msg":"hello"}],"gas_used":10}`,
+	`{"type":"exec-app","success":true,"new_state":"00ff","returndata":"","logs":[],"gas_used":0}`,
+	`{"err_type":"function-failed","template_addr":"BC213FFE5F285ADF9B2DF9975A98A8F3B8106BF7",` +
+		`"app_addr":"BC213FFE5F285ADF9B2DF9975A98A8F3B8106BF7","func":"run","message":"Mayday"}`,
+}
+
+func TestCodec_DecodeReceiptJSON_Valid(t *testing.T) {
+	req := require.New(t)
+
+	v, err := decodeReceiptJSON([]byte(validReceiptsJSON[0]))
+	req.NoError(err)
+	req.Equal(&common.ReceiptDeployTemplate{
+		Success:      true,
+		TemplateAddr: common.BytesToAddress(mustDecodeHex(t, "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7")),
+		GasUsed:      196000,
+	}, v)
+
+	v, err = decodeReceiptJSON([]byte(validReceiptsJSON[1]))
+	req.NoError(err)
+	req.Equal(&common.ReceiptSpawnApp{
+		Success:    true,
+		AppAddr:    common.BytesToAddress(mustDecodeHex(t, "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7")),
+		State:      []byte{0x00, 0xff},
+		Returndata: []byte{0x01, 0x02},
+		Logs:       []common.Log{{Code: 100, Data: []byte("hello")}},
+		GasUsed:    10,
+	}, v)
+
+	v, err = decodeReceiptJSON([]byte(validReceiptsJSON[2]))
+	req.NoError(err)
+	req.Equal(&common.ReceiptExecApp{
+		Success:    true,
+		NewState:   []byte{0x00, 0xff},
+		Returndata: []byte{},
+		Logs:       []common.Log{},
+	}, v)
+}
+
+func TestCodec_DecodeReceiptJSON_Invalid(t *testing.T) {
+	cases := []struct {
+		json string
+		err  string
+	}{
+		{`[]`, "invalid receipt: json: cannot unmarshal array into Go value of type codec.jsonObject"},
+		{`null`, "invalid receipt: not a json object"},
+		{`{}`, "invalid receipt: missing `type` field"},
+		{`{"type":1}`, "invalid receipt: invalid `type` field; expected a string, given: float64"},
+		{`{"type":"unknown"}`, "invalid receipt: invalid receipt type: unknown"},
+		{`{"err_type":"unknown"}`, "invalid receipt: invalid error type: unknown"},
+		{`{"err_type":"app-not-found"}`, "invalid app-not-found error receipt: missing `app_addr` field"},
+		{`{"err_type":"app-not-found","app_addr":"zz"}`,
+			"invalid app-not-found error receipt: invalid `app_addr` field; invalid hex string: encoding/hex: invalid byte: U+007A 'z'"},
+		{`{"err_type":"app-not-found","app_addr":"00"}`,
+			"invalid app-not-found error receipt: invalid `app_addr` field; invalid address size; expected: 20, given: 1"},
+		{`{"type":"deploy-template","success":"true"}`,
+			"invalid deploy-template receipt: invalid `success` field; expected a bool, given: string"},
+		{`{"type":"deploy-template","success":true,"addr":"BC213FFE5F285ADF9B2DF9975A98A8F3B8106BF7","gas_used":-1}`,
+			"invalid deploy-template receipt: invalid `gas_used` field; expected an unsigned integer, given: -1"},
+		{`{"type":"deploy-template","success":true,"addr":"BC213FFE5F285ADF9B2DF9975A98A8F3B8106BF7","gas_used":1.5}`,
+			"invalid deploy-template receipt: invalid `gas_used` field; expected an unsigned integer, given: 1.5"},
+		{`{"type":"exec-app","success":true,"new_state":"","returndata":"","logs":[1]}`,
+			"invalid exec-app receipt: invalid `logs` field; log #0: expected an object, given: float64"},
+		{`{"type":"exec-app","success":true,"new_state":"","returndata":"","logs":[{"code":1}]}`,
+			"invalid exec-app receipt: invalid `logs` field; log #0: missing `msg` field"},
+		{`{"type":"exec-app","success":true,"new_state":"","returndata":"","logs":[{"code":256,"msg":""}]}`,
+			"invalid exec-app receipt: invalid `logs` field; log #0: invalid `code` field; expected a uint8, given: 256"},
+	}
+
+	for _, c := range cases {
+		_, err := decodeReceiptJSON([]byte(c.json))
+		require.EqualError(t, err, c.err, c.json)
+	}
+}
+
+// TestCodec_DecodeReceiptJSON_Fuzz mutates valid receipts randomly,
+// and verifies that decoding never panics.
+func TestCodec_DecodeReceiptJSON_Fuzz(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	randomValue := func() interface{} {
+		switch rnd.Intn(9) {
+		case 0:
+			return nil
+		case 1:
+			return rnd.Intn(2) == 0
+		case 2:
+			return rnd.NormFloat64() * 1e6
+		case 3:
+			return -rnd.Float64()
+		case 4:
+			return hex.EncodeToString(randomBytes(rnd, rnd.Intn(40)))
+		case 5:
+			return string(randomBytes(rnd, rnd.Intn(10)))
+		case 6:
+			return []interface{}{map[string]interface{}{"code": rnd.Intn(300), "msg": 1}}
+		case 7:
+			return map[string]interface{}{}
+		default:
+			return []interface{}{rnd.Int()}
+		}
+	}
+
+	for i := 0; i < 10000; i++ {
+		var v map[string]interface{}
+		base := validReceiptsJSON[rnd.Intn(len(validReceiptsJSON))]
+		require.NoError(t, json.Unmarshal([]byte(base), &v))
+
+		for j := rnd.Intn(3) + 1; j > 0; j-- {
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			key := keys[rnd.Intn(len(keys))]
+
+			if rnd.Intn(4) == 0 {
+				delete(v, key)
+			} else {
+				v[key] = randomValue()
+			}
+		}
+
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		require.NotPanics(t, func() { decodeReceiptJSON(data) }, "%s", data)
+
+		// Random bytes, mostly invalid JSON.
+		data = randomBytes(rnd, rnd.Intn(64))
+		require.NotPanics(t, func() { decodeReceiptJSON(data) }, "%x", data)
+	}
+}
+
+// TestCodec_DecodeReceipt_Fuzz verifies that decoding random raw receipts never panics.
+func TestCodec_DecodeReceipt_Fuzz(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	valid, err := hex.DecodeString("0001bc213ffe5f285adf9b2df9975a98a8f3b8106bf7a02fda0000")
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		raw := append([]byte(nil), valid...)
+		switch rnd.Intn(3) {
+		case 0:
+			raw = raw[:rnd.Intn(len(raw))]
+		case 1:
+			raw[rnd.Intn(len(raw))] = byte(rnd.Intn(256))
+		default:
+			raw = randomBytes(rnd, rnd.Intn(64))
+		}
+
+		require.NotPanics(t, func() {
+			DecodeReceiptDeployTemplate(raw)
+			DecodeReceiptSpawnApp(raw)
+			DecodeReceiptExecApp(raw)
+		}, "%x", raw)
+	}
+}
+
+func randomBytes(rnd *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	rnd.Read(b)
+	return b
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// TestCodec_Concurrent hammers all encoders and decoders in parallel,
+// and is intended to be run with the race detector.
+func TestCodec_Concurrent(t *testing.T) {
+	req := require.New(t)
+
+	c, err := New(WithPoolSize(4))
+	req.NoError(err)
+
+	templateAddr := mustDecodeHex(t, "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7")
+	rawReceipt := mustDecodeHex(t, "0001bc213ffe5f285adf9b2df9975a98a8f3b8106bf7a02fda0000")
+
+	calls := []func() (interface{}, error){
+		func() (interface{}, error) { return c.EncodeTxDeployTemplate(0, "counter", []byte{0, 1, 2}, []byte{4}) },
+		func() (interface{}, error) {
+			return c.EncodeTxSpawnApp(0, templateAddr, "counter", "initialize", []byte{1})
+		},
+		func() (interface{}, error) { return c.EncodeTxExecApp(0, templateAddr, "counter_add", []byte{1}) },
+		func() (interface{}, error) { return c.EncodeCallData([]string{"i32", "u64"}, []int{-10, 10}) },
+		func() (interface{}, error) { return DecodeReceiptDeployTemplate(rawReceipt) },
+		func() (interface{}, error) { return c.decodeReceiptWasm(rawReceipt) },
+	}
+
+	rawReturndata, err := c.EncodeCallData([]string{"u32"}, []int{10})
+	req.NoError(err)
+	calls = append(calls, func() (interface{}, error) { return c.DecodeReturndata(rawReturndata) })
+
+	expected := make([]interface{}, len(calls))
+	for i, call := range calls {
+		expected[i], err = call()
+		req.NoError(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				n := (g + i) % len(calls)
+				actual, err := calls[n]()
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !reflect.DeepEqual(expected[n], actual) {
+					errs <- fmt.Errorf("call #%v; expected: %v, given: %v", n, expected[n], actual)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		req.NoError(err)
+	}
+}
+
+func TestNew_InvalidPoolSize(t *testing.T) {
+	_, err := New(WithPoolSize(0))
+	require.EqualError(t, err, "invalid pool size: 0")
+}
+
+func TestNew_WithWasmBytes(t *testing.T) {
+	req := require.New(t)
+
+	_, err := New(WithWasmBytes(nil))
+	req.EqualError(err, "missing codec wasm")
+
+	_, err = New(WithWasmBytes([]byte("not a wasm module")))
+	req.Error(err)
+	req.Contains(err.Error(), "invalid codec wasm: ")
+
+	// A copy of the bundled codec wasm, as loaded by programs which ship it separately.
+	wasm := append([]byte{}, bundledWasm...)
+	c, err := New(WithWasmBytes(wasm))
+	req.NoError(err)
+
+	calldata, err := c.EncodeCallData([]string{"u32"}, []int{10})
+	req.NoError(err)
+	req.Equal([]byte{abiU32, 10}, calldata)
+}
+
+func mustDefaultCodec(tb testing.TB) *Codec {
+	c, err := defaultCodec()
+	require.NoError(tb, err)
+	return c
+}
+
+// TestCodec_MemoryStaysFlat verifies that the buffers of codec calls are freed,
+// so the memory of the codec instance doesn't grow over time.
+func TestCodec_MemoryStaysFlat(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping long-running test in short mode")
+	}
+
+	const iterations = 1000000
+
+	req := require.New(t)
+
+	c, err := New(WithPoolSize(1))
+	req.NoError(err)
+
+	appAddr := mustDecodeHex(t, "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7")
+	rawReceipt := mustDecodeHex(t, "0001bc213ffe5f285adf9b2df9975a98a8f3b8106bf7a02fda0000")
+
+	roundTrip := func(i int) {
+		calldata, err := c.EncodeCallData([]string{"u32"}, []int{i % 1000})
+		req.NoError(err)
+
+		_, err = c.EncodeTxExecApp(0, appAddr, "counter_add", calldata)
+		req.NoError(err)
+
+		_, err = c.DecodeReturndata(calldata)
+		req.NoError(err)
+
+		_, err = c.decodeReceiptWasm(rawReceipt)
+		req.NoError(err)
+	}
+
+	// Let the memory reach its steady state.
+	for i := 0; i < 1000; i++ {
+		roundTrip(i)
+	}
+	before := codecMemorySize(t, c)
+
+	for i := 0; i < iterations; i++ {
+		roundTrip(i)
+	}
+	req.Equal(before, codecMemorySize(t, c))
+}
+
+func codecMemorySize(t *testing.T, c *Codec) uint {
+	inst, err := c.pool.acquire()
+	require.NoError(t, err)
+	defer c.pool.release(inst)
+
+	mem, err := inst.inner.Exports.GetMemory("memory")
+	require.NoError(t, err)
+
+	return mem.DataSize()
+}
@@ -1,6 +1,11 @@
 // Package codec provides a gateway for Go programs to use the `svm_codec.wasm` artifact,
 // instantiated by [wasmer] via [wasmer-go].
 //
+// The codec wasm is bundled into the package, and may be replaced using `WithWasmBytes`.
+//
+// A `Codec` is backed by a bounded pool of wasm instances, and is safe for concurrent use.
+// The package-level functions share a default `Codec`, which is created on first use.
+//
 // [wasmer]: https://github.com/wasmerio/wasmer
 // [wasmer-go]: https://github.com/wasmerio/wasmer-go
 //
@@ -0,0 +1,178 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wasmerio/wasmer-go/wasmer"
+)
+
+const (
+	markerErr = 0
+	markerOk  = 1
+)
+
+// instance is a single instance of the codec wasm.
+// It isn't safe for concurrent use; see `Codec` for that.
+type instance struct {
+	inner *wasmer.Instance
+
+	// broken is set once a call into the instance fails (e.g., traps),
+	// after which its memory can't be trusted, and it shouldn't be reused.
+	broken bool
+}
+
+func newInstance(module *wasmer.Module) (*instance, error) {
+	inner, err := wasmer.NewInstance(module, wasmer.NewImportObject())
+	if err != nil {
+		return nil, err
+	}
+
+	return &instance{inner: inner}, nil
+}
+
+// call calls an exported codec function, passing it `input` and returning its output.
+// Both the input and output buffers are freed before returning.
+func (inst *instance) call(name string, input []byte) ([]byte, error) {
+	argPtr, err := inst.newBuffer(input)
+	if err != nil {
+		return nil, err
+	}
+	defer inst.bufferFree(argPtr)
+
+	retPtr, err := inst.callFunc(name, argPtr)
+	if err != nil {
+		return nil, err
+	}
+	defer inst.bufferFree(retPtr)
+
+	return inst.loadBuffer(retPtr)
+}
+
+func (inst *instance) newBuffer(data []byte) (int32, error) {
+	length := int32(len(data))
+	ptr, err := inst.bufferAlloc(length)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := inst.copyToBuffer(ptr, data); err != nil {
+		inst.bufferFree(ptr)
+		return 0, err
+	}
+
+	return ptr, nil
+}
+
+func (inst *instance) copyToBuffer(ptr int32, data []byte) error {
+	length := int32(len(data))
+
+	bufferLength, err := inst.bufferLength(ptr)
+	if err != nil {
+		return err
+	}
+	if length != bufferLength {
+		return fmt.Errorf("allocated buffer size isn't sufficient; allocated: %v, got: %v", length, bufferLength)
+	}
+
+	dataPtr, err := inst.bufferDataPtr(ptr)
+	if err != nil {
+		return err
+	}
+
+	mem, err := inst.inner.Exports.GetMemory("memory")
+	if err != nil {
+		return err
+	}
+
+	memData := mem.Data()
+	if dataPtr < 0 || int(dataPtr)+len(data) > len(memData) {
+		return fmt.Errorf("allocated buffer is out of memory bounds; offset: %v, length: %v", dataPtr, length)
+	}
+	copy(memData[dataPtr:], data)
+
+	return nil
+}
+
+func (inst *instance) loadBuffer(ptr int32) ([]byte, error) {
+	length, err := inst.bufferLength(ptr)
+	if err != nil {
+		return nil, err
+	}
+
+	dataPtr, err := inst.bufferDataPtr(ptr)
+	if err != nil {
+		return nil, err
+	}
+
+	mem, err := inst.inner.Exports.GetMemory("memory")
+	if err != nil {
+		return nil, err
+	}
+
+	memData := mem.Data()
+	if length < 1 || dataPtr < 0 || int(dataPtr)+int(length) > len(memData) {
+		return nil, fmt.Errorf("invalid result buffer; offset: %v, length: %v", dataPtr, length)
+	}
+
+	// Copy the buffer, since the memory might be re-used once it's freed.
+	buf := make([]byte, length)
+	copy(buf, memData[dataPtr:dataPtr+length])
+	marker := buf[0]
+	data := buf[1:]
+
+	switch marker {
+	case markerErr:
+		return nil, errors.New(string(data))
+	case markerOk:
+		return data, nil
+	default:
+		return nil, fmt.Errorf("invalid result buffer marker: %v", marker)
+	}
+}
+
+func (inst *instance) bufferAlloc(size int32) (int32, error) {
+	return inst.callFunc("wasm_alloc", size)
+}
+
+// bufferFree frees a buffer allocated by `wasm_alloc`, or returned by an exported codec function.
+// A failure marks the instance as broken, rather than failing the call which owns the buffer.
+func (inst *instance) bufferFree(buf int32) {
+	fn, err := inst.inner.Exports.GetFunction("wasm_free")
+	if err != nil {
+		inst.broken = true
+		return
+	}
+
+	if _, err := fn(buf); err != nil {
+		inst.broken = true
+	}
+}
+
+func (inst *instance) bufferLength(buf int32) (int32, error) {
+	return inst.callFunc("wasm_buffer_length", buf)
+}
+
+func (inst *instance) bufferDataPtr(buf int32) (int32, error) {
+	return inst.callFunc("wasm_buffer_data", buf)
+}
+
+// callFunc calls an exported codec function, which takes and returns a single i32.
+func (inst *instance) callFunc(name string, arg int32) (int32, error) {
+	fn, err := inst.inner.Exports.GetFunction(name)
+	if err != nil {
+		return 0, err
+	}
+
+	ret, err := fn(arg)
+	if err != nil {
+		inst.broken = true
+		return 0, err
+	}
+
+	i, ok := ret.(int32)
+	if !ok {
+		return 0, fmt.Errorf("invalid `%v` result; expected: int32, given: %T", name, ret)
+	}
+
+	return i, nil
+}
@@ -0,0 +1,194 @@
+package codec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"go-svm/common"
+	"math"
+)
+
+// jsonObject is a decoded JSON object, providing checked access to its fields.
+type jsonObject map[string]interface{}
+
+// jsonField decodes a single field of a jsonObject.
+type jsonField func() error
+
+// decode decodes the given fields, and stops at the first failure.
+func (v jsonObject) decode(fields ...jsonField) error {
+	for _, f := range fields {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v jsonObject) field(key string) (interface{}, error) {
+	f, ok := v[key]
+	if !ok {
+		return nil, fmt.Errorf("missing `%v` field", key)
+	}
+
+	return f, nil
+}
+
+func (v jsonObject) string(key string) (string, error) {
+	f, err := v.field(key)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := f.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid `%v` field; expected a string, given: %T", key, f)
+	}
+
+	return s, nil
+}
+
+func (v jsonObject) bool(key string) (bool, error) {
+	f, err := v.field(key)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := f.(bool)
+	if !ok {
+		return false, fmt.Errorf("invalid `%v` field; expected a bool, given: %T", key, f)
+	}
+
+	return b, nil
+}
+
+func (v jsonObject) uint64(key string) (uint64, error) {
+	f, err := v.field(key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := f.(float64)
+	if !ok {
+		return 0, fmt.Errorf("invalid `%v` field; expected a number, given: %T", key, f)
+	}
+	if n < 0 || n > math.MaxUint64 || n != math.Trunc(n) {
+		return 0, fmt.Errorf("invalid `%v` field; expected an unsigned integer, given: %v", key, n)
+	}
+
+	return uint64(n), nil
+}
+
+func (v jsonObject) hex(key string) ([]byte, error) {
+	s, err := v.string(key)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid `%v` field; invalid hex string: %v", key, err)
+	}
+
+	return b, nil
+}
+
+func (v jsonObject) address(key string) (common.Address, error) {
+	b, err := v.hex(key)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(b) != common.AddressSize {
+		return common.Address{}, fmt.Errorf("invalid `%v` field; invalid address size; expected: %v, given: %v",
+			key, common.AddressSize, len(b))
+	}
+
+	return common.BytesToAddress(b), nil
+}
+
+func (v jsonObject) array(key string) ([]interface{}, error) {
+	f, err := v.field(key)
+	if err != nil {
+		return nil, err
+	}
+
+	a, ok := f.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid `%v` field; expected an array, given: %T", key, f)
+	}
+
+	return a, nil
+}
+
+func (v jsonObject) logs(key string) ([]common.Log, error) {
+	items, err := v.array(key)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]common.Log, len(items))
+	for i, item := range items {
+		log, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid `%v` field; log #%v: expected an object, given: %T", key, i, item)
+		}
+
+		var code uint64
+		var msg string
+		if err := jsonObject(log).decode(
+			jsonObject(log).uint64Field("code", &code),
+			jsonObject(log).stringField("msg", &msg),
+		); err != nil {
+			return nil, fmt.Errorf("invalid `%v` field; log #%v: %v", key, i, err)
+		}
+
+		if code > math.MaxUint8 {
+			return nil, fmt.Errorf("invalid `%v` field; log #%v: invalid `code` field; expected a uint8, given: %v", key, i, code)
+		}
+
+		logs[i] = common.Log{Code: uint8(code), Data: []byte(msg)}
+	}
+
+	return logs, nil
+}
+
+func (v jsonObject) stringField(key string, dst *string) jsonField {
+	return func() (err error) {
+		*dst, err = v.string(key)
+		return
+	}
+}
+
+func (v jsonObject) boolField(key string, dst *bool) jsonField {
+	return func() (err error) {
+		*dst, err = v.bool(key)
+		return
+	}
+}
+
+func (v jsonObject) uint64Field(key string, dst *uint64) jsonField {
+	return func() (err error) {
+		*dst, err = v.uint64(key)
+		return
+	}
+}
+
+func (v jsonObject) hexField(key string, dst *[]byte) jsonField {
+	return func() (err error) {
+		*dst, err = v.hex(key)
+		return
+	}
+}
+
+func (v jsonObject) addressField(key string, dst *common.Address) jsonField {
+	return func() (err error) {
+		*dst, err = v.address(key)
+		return
+	}
+}
+
+func (v jsonObject) logsField(key string, dst *[]common.Log) jsonField {
+	return func() (err error) {
+		*dst, err = v.logs(key)
+		return
+	}
+}
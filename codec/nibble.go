@@ -0,0 +1,159 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"go-svm/common"
+	"unicode/utf8"
+)
+
+// maxVersionNibbles is the maximum number of nibbles of an encoded version.
+const maxVersionNibbles = 10
+
+var errUnexpectedEOF = errors.New("unexpected end of data")
+
+// nibbleDecoder reads the fields of nibble-aligned data, such as raw receipts and transactions.
+// The first failure is kept in `err`, after which all reads return zero values.
+type nibbleDecoder struct {
+	data []byte
+
+	// pos is the offset of the next nibble to read.
+	pos int
+
+	err error
+}
+
+func (d *nibbleDecoder) fail(field string, err error) {
+	if d.err == nil {
+		d.err = fmt.Errorf("invalid `%v` field; %v", field, err)
+	}
+}
+
+func (d *nibbleDecoder) nibble(field string) byte {
+	if d.err != nil {
+		return 0
+	}
+	if d.pos >= 2*len(d.data) {
+		d.fail(field, errUnexpectedEOF)
+		return 0
+	}
+
+	b := d.data[d.pos/2]
+	if d.pos%2 == 0 {
+		b >>= 4
+	}
+	d.pos++
+
+	return b & 0x0f
+}
+
+func (d *nibbleDecoder) byte(field string) byte {
+	hi := d.nibble(field)
+	lo := d.nibble(field)
+	return hi<<4 | lo
+}
+
+func (d *nibbleDecoder) bytes(field string, n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > (2*len(d.data)-d.pos)/2 {
+		d.fail(field, errUnexpectedEOF)
+		return nil
+	}
+
+	b := make([]byte, n)
+	if d.pos%2 == 0 {
+		copy(b, d.data[d.pos/2:])
+		d.pos += 2 * n
+		return b
+	}
+
+	for i := range b {
+		b[i] = d.byte(field)
+	}
+	return b
+}
+
+func (d *nibbleDecoder) uint32(field string) uint32 {
+	var v uint32
+	for _, b := range d.bytes(field, 4) {
+		v = v<<8 | uint32(b)
+	}
+
+	return v
+}
+
+// bytes32 decodes bytes prefixed by their length, encoded as a big-endian uint32.
+// The length is checked against the remaining data before it's converted to an int,
+// since it may not fit into one on 32-bit platforms.
+func (d *nibbleDecoder) bytes32(field string) []byte {
+	n := d.uint32(field)
+	if d.err == nil && uint64(n) > uint64(2*len(d.data)-d.pos)/2 {
+		d.fail(field, errUnexpectedEOF)
+		return nil
+	}
+
+	return d.bytes(field, int(n))
+}
+
+func (d *nibbleDecoder) bool(field string) bool {
+	switch n := d.nibble(field); n {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		d.fail(field, fmt.Errorf("invalid bool: %v", n))
+		return false
+	}
+}
+
+// version decodes a version, encoded as a sequence of nibbles.
+// Each nibble holds 3 bits of the version, and has its MSB set, except for the last one.
+func (d *nibbleDecoder) version(field string) int {
+	var v int
+	for i := 0; i < maxVersionNibbles; i++ {
+		n := d.nibble(field)
+		v = v<<3 | int(n&0x07)
+		if n&0x08 == 0 {
+			return v
+		}
+	}
+
+	d.fail(field, fmt.Errorf("version exceeds %v nibbles", maxVersionNibbles))
+	return 0
+}
+
+// varuint14 decodes a number of up to 14 bits. The 2 MSBs of the first nibble hold
+// the number of nibbles that follow it, and the rest of the bits hold the number itself.
+func (d *nibbleDecoder) varuint14(field string) int {
+	first := d.nibble(field)
+
+	v := int(first & 0x03)
+	for i := 0; i < int(first>>2); i++ {
+		v = v<<4 | int(d.nibble(field))
+	}
+
+	return v
+}
+
+func (d *nibbleDecoder) address(field string) common.Address {
+	return common.BytesToAddress(d.bytes(field, common.AddressSize))
+}
+
+func (d *nibbleDecoder) string(field string) string {
+	b := d.bytes(field, d.varuint14(field))
+	if d.err != nil {
+		return ""
+	}
+
+	switch {
+	case len(b) == 0:
+		d.fail(field, errors.New("empty string"))
+	case !utf8.Valid(b):
+		d.fail(field, errors.New("invalid UTF-8 string"))
+	}
+
+	return string(b)
+}
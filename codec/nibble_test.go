@@ -0,0 +1,102 @@
+package codec
+
+import (
+	"go-svm/common"
+)
+
+// nibbleWriter encodes nibble-aligned data, mirroring `nibbleDecoder`.
+type nibbleWriter struct {
+	nibbles []byte
+}
+
+func (w *nibbleWriter) nibble(n byte) {
+	w.nibbles = append(w.nibbles, n&0x0f)
+}
+
+func (w *nibbleWriter) byte(b byte) {
+	w.nibble(b >> 4)
+	w.nibble(b)
+}
+
+func (w *nibbleWriter) bytes(b []byte) {
+	for _, v := range b {
+		w.byte(v)
+	}
+}
+
+func (w *nibbleWriter) version(v int) {
+	var groups []byte
+	for {
+		groups = append([]byte{byte(v & 0x07)}, groups...)
+		v >>= 3
+		if v == 0 {
+			break
+		}
+	}
+
+	for i, g := range groups {
+		if i < len(groups)-1 {
+			g |= 0x08
+		}
+		w.nibble(g)
+	}
+}
+
+func (w *nibbleWriter) bool(b bool) {
+	if b {
+		w.nibble(1)
+	} else {
+		w.nibble(0)
+	}
+}
+
+func (w *nibbleWriter) varuint14(n int) {
+	extra := 0
+	for n>>(2+4*extra) != 0 {
+		extra++
+	}
+
+	w.nibble(byte(extra<<2 | n>>(4*extra)))
+	for i := extra - 1; i >= 0; i-- {
+		w.nibble(byte(n >> (4 * i)))
+	}
+}
+
+func (w *nibbleWriter) gas(gas uint64) {
+	n := 1
+	for gas>>(8*n) != 0 && n < 8 {
+		n++
+	}
+
+	w.nibble(0x08 | byte(n-1))
+	for i := n - 1; i >= 0; i-- {
+		w.byte(byte(gas >> (8 * i)))
+	}
+}
+
+func (w *nibbleWriter) string(s string) {
+	w.varuint14(len(s))
+	w.bytes([]byte(s))
+}
+
+func (w *nibbleWriter) logs(logs []common.Log) {
+	w.byte(byte(len(logs)))
+	for _, l := range logs {
+		w.byte(byte(len(l.Data)))
+		w.bytes(l.Data)
+		w.byte(l.Code)
+	}
+}
+
+func (w *nibbleWriter) data() []byte {
+	nibbles := w.nibbles
+	if len(nibbles)%2 == 1 {
+		nibbles = append(nibbles, 0)
+	}
+
+	b := make([]byte, len(nibbles)/2)
+	for i := range b {
+		b[i] = nibbles[2*i]<<4 | nibbles[2*i+1]
+	}
+	return b
+}
@@ -0,0 +1,53 @@
+package codec
+
+// instancePool is a bounded pool of codec instances, which are created lazily.
+type instancePool struct {
+	newInstance func() (*instance, error)
+
+	// idle holds the instances which aren't in use.
+	idle chan *instance
+
+	// slots holds a token for each instance which exists, whether idle or in use.
+	slots chan struct{}
+}
+
+func newInstancePool(size int, newInstance func() (*instance, error)) *instancePool {
+	return &instancePool{
+		newInstance: newInstance,
+		idle:        make(chan *instance, size),
+		slots:       make(chan struct{}, size),
+	}
+}
+
+// acquire returns an idle instance, creates a new one if the pool isn't full,
+// or otherwise blocks until an instance is released.
+func (p *instancePool) acquire() (*instance, error) {
+	select {
+	case inst := <-p.idle:
+		return inst, nil
+	default:
+	}
+
+	select {
+	case inst := <-p.idle:
+		return inst, nil
+	case p.slots <- struct{}{}:
+		inst, err := p.newInstance()
+		if err != nil {
+			<-p.slots
+			return nil, err
+		}
+		return inst, nil
+	}
+}
+
+// release returns an instance to the pool. Broken instances are discarded,
+// making room for a new instance to be created in their place.
+func (p *instancePool) release(inst *instance) {
+	if inst.broken {
+		<-p.slots
+		return
+	}
+
+	p.idle <- inst
+}
@@ -0,0 +1,119 @@
+package codec
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInstancePool(t *testing.T) {
+	req := require.New(t)
+
+	created := 0
+	pool := newInstancePool(2, func() (*instance, error) {
+		created++
+		return &instance{}, nil
+	})
+
+	inst1, err := pool.acquire()
+	req.NoError(err)
+	inst2, err := pool.acquire()
+	req.NoError(err)
+	req.NotSame(inst1, inst2)
+	req.Equal(2, created)
+
+	acquired := make(chan *instance)
+	go func() {
+		inst, _ := pool.acquire()
+		acquired <- inst
+	}()
+
+	select {
+	case <-acquired:
+		req.Fail("acquired an instance beyond the pool size")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	pool.release(inst1)
+	req.Same(inst1, <-acquired)
+	req.Equal(2, created)
+}
+
+func TestInstancePool_Broken(t *testing.T) {
+	req := require.New(t)
+
+	pool := newInstancePool(1, func() (*instance, error) {
+		return &instance{}, nil
+	})
+
+	inst, err := pool.acquire()
+	req.NoError(err)
+
+	inst.broken = true
+	pool.release(inst)
+
+	newInst, err := pool.acquire()
+	req.NoError(err)
+	req.NotSame(inst, newInst)
+}
+
+func TestInstancePool_CreateError(t *testing.T) {
+	req := require.New(t)
+
+	fail := true
+	pool := newInstancePool(1, func() (*instance, error) {
+		if fail {
+			return nil, errors.New("failed to instantiate")
+		}
+		return &instance{}, nil
+	})
+
+	_, err := pool.acquire()
+	req.EqualError(err, "failed to instantiate")
+
+	// The failed attempt doesn't take up the pool's only slot.
+	fail = false
+	_, err = pool.acquire()
+	req.NoError(err)
+}
+
+func TestInstancePool_Concurrent(t *testing.T) {
+	const size = 4
+
+	var mu sync.Mutex
+	inUse, maxInUse := 0, 0
+
+	pool := newInstancePool(size, func() (*instance, error) {
+		return &instance{}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				inst, _ := pool.acquire()
+
+				mu.Lock()
+				inUse++
+				if inUse > maxInUse {
+					maxInUse = inUse
+				}
+				mu.Unlock()
+
+				mu.Lock()
+				inUse--
+				mu.Unlock()
+
+				pool.release(inst)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, maxInUse, size)
+}
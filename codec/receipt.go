@@ -0,0 +1,213 @@
+package codec
+
+import (
+	"fmt"
+	"go-svm/common"
+)
+
+// Receipt types, as encoded in the first byte of a raw receipt.
+const (
+	receiptTypeDeployTemplate = 0
+	receiptTypeSpawnApp       = 1
+	receiptTypeExecApp        = 2
+)
+
+// Receipt error types, as encoded in failed receipts.
+const (
+	receiptErrOOG                 = 0
+	receiptErrTemplateNotFound    = 1
+	receiptErrAppNotFound         = 2
+	receiptErrCompilationFailed   = 3
+	receiptErrInstantiationFailed = 4
+	receiptErrFunctionNotFound    = 5
+	receiptErrFunctionFailed      = 6
+)
+
+// stateSize is the size of an app state, in bytes.
+const stateSize = 32
+
+var receiptTypeNames = map[byte]string{
+	receiptTypeDeployTemplate: "deploy-template",
+	receiptTypeSpawnApp:       "spawn-app",
+	receiptTypeExecApp:        "exec-app",
+}
+
+var receiptErrNames = map[byte]string{
+	receiptErrOOG:                 "oog",
+	receiptErrTemplateNotFound:    "template-not-found",
+	receiptErrAppNotFound:         "app-not-found",
+	receiptErrCompilationFailed:   "compilation-failed",
+	receiptErrInstantiationFailed: "instantiation-failed",
+	receiptErrFunctionNotFound:    "function-not-found",
+	receiptErrFunctionFailed:      "function-failed",
+}
+
+// decodeReceipt decodes a raw receipt natively, without going through the codec wasm.
+// Raw receipts are nibble-aligned, and are laid out as follows:
+//
+//	+-----------+-------------+-------------+-----------------------+
+//	|  type     |  version    |  success    |  receipt fields       |
+//	| (1 byte)  | (nibbles)   | (1 nibble)  |                       |
+//	+-----------+-------------+-------------+-----------------------+
+//
+// On failure, the receipt fields are replaced with the error fields.
+func decodeReceipt(rawReceipt []byte) (interface{}, error) {
+	d := &nibbleDecoder{data: rawReceipt}
+
+	ty := d.byte("type")
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid receipt: %v", d.err)
+	}
+
+	name, ok := receiptTypeNames[ty]
+	if !ok {
+		return nil, fmt.Errorf("invalid receipt: invalid receipt type: %v", ty)
+	}
+
+	version := d.version("version")
+	success := d.bool("success")
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid %v receipt: %v", name, d.err)
+	}
+
+	if !success {
+		return nil, d.receiptError()
+	}
+
+	var receipt interface{}
+	switch ty {
+	case receiptTypeDeployTemplate:
+		receipt = &common.ReceiptDeployTemplate{
+			Success:      true,
+			Version:      version,
+			TemplateAddr: d.address("addr"),
+			GasUsed:      d.gas("gas_used"),
+		}
+		d.logs("logs")
+	case receiptTypeSpawnApp:
+		receipt = &common.ReceiptSpawnApp{
+			Success:    true,
+			Version:    version,
+			AppAddr:    d.address("app"),
+			State:      d.bytes("state", stateSize),
+			Returndata: d.bytes("returndata", d.varuint14("returndata")),
+			GasUsed:    d.gas("gas_used"),
+			Logs:       d.logs("logs"),
+		}
+	case receiptTypeExecApp:
+		receipt = &common.ReceiptExecApp{
+			Success:    true,
+			Version:    version,
+			NewState:   d.bytes("new_state", stateSize),
+			Returndata: d.bytes("returndata", d.varuint14("returndata")),
+			GasUsed:    d.gas("gas_used"),
+			Logs:       d.logs("logs"),
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid %v receipt: %v", name, d.err)
+	}
+
+	return receipt, nil
+}
+
+// receiptError decodes the error of a failed receipt. The logs of a failed receipt are discarded.
+func (d *nibbleDecoder) receiptError() error {
+	errType := d.nibble("err_type")
+	if d.err != nil {
+		return fmt.Errorf("invalid receipt: %v", d.err)
+	}
+
+	name, ok := receiptErrNames[errType]
+	if !ok {
+		return fmt.Errorf("invalid receipt: invalid error type: %v", errType)
+	}
+
+	d.logs("logs")
+
+	var receiptErr error
+	switch errType {
+	case receiptErrOOG:
+		receiptErr = common.ErrOutOfGas
+	case receiptErrTemplateNotFound:
+		receiptErr = &common.TemplateNotFoundError{Addr: d.address("template_addr")}
+	case receiptErrAppNotFound:
+		receiptErr = &common.AppNotFoundError{Addr: d.address("app_addr")}
+	case receiptErrCompilationFailed:
+		receiptErr = &common.CompilationFailedError{
+			TemplateAddr: d.address("template_addr"),
+			AppAddr:      d.address("app_addr"),
+			Msg:          d.string("message"),
+		}
+	case receiptErrInstantiationFailed:
+		receiptErr = &common.InstantiationFailedError{
+			TemplateAddr: d.address("template_addr"),
+			AppAddr:      d.address("app_addr"),
+			Msg:          d.string("message"),
+		}
+	case receiptErrFunctionNotFound:
+		receiptErr = &common.FunctionNotFoundError{
+			TemplateAddr: d.address("template_addr"),
+			AppAddr:      d.address("app_addr"),
+			Func:         d.string("func"),
+		}
+	case receiptErrFunctionFailed:
+		receiptErr = &common.FunctionFailedError{
+			TemplateAddr: d.address("template_addr"),
+			AppAddr:      d.address("app_addr"),
+			Func:         d.string("func"),
+			Msg:          d.string("message"),
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("invalid %v error receipt: %v", name, d.err)
+	}
+
+	return receiptErr
+}
+
+// gas decodes a gas amount. A first nibble of the form `1xxx` is followed by
+// `xxx + 1` bytes holding the amount in big-endian order, while `0101` stands for zero.
+func (d *nibbleDecoder) gas(field string) uint64 {
+	first := d.nibble(field)
+	if d.err != nil {
+		return 0
+	}
+
+	switch {
+	case first == 0x05:
+		return 0
+	case first&0x08 == 0:
+		d.fail(field, fmt.Errorf("invalid gas prefix: %04b", first))
+		return 0
+	}
+
+	var v uint64
+	for _, b := range d.bytes(field, int(first&0x07)+1) {
+		v = v<<8 | uint64(b)
+	}
+
+	return v
+}
+
+// logs decodes a list of logs, encoded as the number of logs (1 byte),
+// followed by each log's data length (1 byte), data and code (1 byte).
+func (d *nibbleDecoder) logs(field string) []common.Log {
+	count := int(d.byte(field))
+	if d.err != nil {
+		return nil
+	}
+
+	logs := make([]common.Log, 0, count)
+	for i := 0; i < count; i++ {
+		data := d.bytes(field, int(d.byte(field)))
+		code := d.byte(field)
+		if d.err != nil {
+			return nil
+		}
+
+		logs = append(logs, common.Log{Code: code, Data: data})
+	}
+
+	return logs
+}
@@ -0,0 +1,398 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"go-svm/common"
+	"math"
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+)
+
+func encodeReceipt(receipt interface{}, logs []common.Log) []byte {
+	w := &nibbleWriter{}
+
+	switch r := receipt.(type) {
+	case *common.ReceiptDeployTemplate:
+		w.byte(receiptTypeDeployTemplate)
+		w.version(r.Version)
+		w.bool(true)
+		w.bytes(r.TemplateAddr[:])
+		w.gas(r.GasUsed)
+		w.logs(logs)
+	case *common.ReceiptSpawnApp:
+		w.byte(receiptTypeSpawnApp)
+		w.version(r.Version)
+		w.bool(true)
+		w.bytes(r.AppAddr[:])
+		w.bytes(r.State)
+		w.varuint14(len(r.Returndata))
+		w.bytes(r.Returndata)
+		w.gas(r.GasUsed)
+		w.logs(r.Logs)
+	case *common.ReceiptExecApp:
+		w.byte(receiptTypeExecApp)
+		w.version(r.Version)
+		w.bool(true)
+		w.bytes(r.NewState)
+		w.varuint14(len(r.Returndata))
+		w.bytes(r.Returndata)
+		w.gas(r.GasUsed)
+		w.logs(r.Logs)
+	default:
+		panic("unexpected receipt")
+	}
+
+	return w.data()
+}
+
+func encodeReceiptError(ty byte, err error, logs []common.Log) []byte {
+	w := &nibbleWriter{}
+	w.byte(ty)
+	w.version(0)
+	w.bool(false)
+
+	writeErr := func(errType byte, template, app *common.Address, strs ...string) {
+		w.nibble(errType)
+		w.logs(logs)
+		if template != nil {
+			w.bytes(template[:])
+		}
+		if app != nil {
+			w.bytes(app[:])
+		}
+		for _, s := range strs {
+			w.string(s)
+		}
+	}
+
+	switch e := err.(type) {
+	case *common.TemplateNotFoundError:
+		writeErr(receiptErrTemplateNotFound, &e.Addr, nil)
+	case *common.AppNotFoundError:
+		writeErr(receiptErrAppNotFound, nil, &e.Addr)
+	case *common.CompilationFailedError:
+		writeErr(receiptErrCompilationFailed, &e.TemplateAddr, &e.AppAddr, e.Msg)
+	case *common.InstantiationFailedError:
+		writeErr(receiptErrInstantiationFailed, &e.TemplateAddr, &e.AppAddr, e.Msg)
+	case *common.FunctionNotFoundError:
+		writeErr(receiptErrFunctionNotFound, &e.TemplateAddr, &e.AppAddr, e.Func)
+	case *common.FunctionFailedError:
+		writeErr(receiptErrFunctionFailed, &e.TemplateAddr, &e.AppAddr, e.Func, e.Msg)
+	default:
+		writeErr(receiptErrOOG, nil, nil)
+	}
+
+	return w.data()
+}
+
+type receiptGenerator struct {
+	rnd *rand.Rand
+}
+
+func (g receiptGenerator) bytes(n int) []byte {
+	b := make([]byte, n)
+	g.rnd.Read(b)
+	return b
+}
+
+func (g receiptGenerator) address() common.Address {
+	return common.BytesToAddress(g.bytes(common.AddressSize))
+}
+
+// text returns a non-empty ASCII string, since the codec wasm decodes strings lossily.
+func (g receiptGenerator) text(maxLen int) string {
+	b := make([]byte, 1+g.rnd.Intn(maxLen))
+	for i := range b {
+		b[i] = byte('a' + g.rnd.Intn(26))
+	}
+	return string(b)
+}
+
+func (g receiptGenerator) logs() []common.Log {
+	logs := make([]common.Log, g.rnd.Intn(4))
+	for i := range logs {
+		logs[i] = common.Log{Code: byte(g.rnd.Intn(256)), Data: []byte(g.text(20))}
+	}
+	return logs
+}
+
+// gas returns a gas amount which survives the codec wasm JSON round trip.
+func (g receiptGenerator) gas() uint64 {
+	return uint64(g.rnd.Int63n(1 << 53))
+}
+
+func (g receiptGenerator) receipt() []byte {
+	ty := byte(g.rnd.Intn(3))
+	if g.rnd.Intn(4) == 0 {
+		return encodeReceiptError(ty, g.receiptError(), g.logs())
+	}
+
+	switch ty {
+	case receiptTypeDeployTemplate:
+		return encodeReceipt(&common.ReceiptDeployTemplate{
+			Success:      true,
+			TemplateAddr: g.address(),
+			GasUsed:      g.gas(),
+		}, g.logs())
+	case receiptTypeSpawnApp:
+		return encodeReceipt(&common.ReceiptSpawnApp{
+			Success:    true,
+			AppAddr:    g.address(),
+			State:      g.bytes(stateSize),
+			Returndata: g.bytes(g.rnd.Intn(300)),
+			Logs:       g.logs(),
+			GasUsed:    g.gas(),
+		}, nil)
+	default:
+		return encodeReceipt(&common.ReceiptExecApp{
+			Success:    true,
+			NewState:   g.bytes(stateSize),
+			Returndata: g.bytes(g.rnd.Intn(300)),
+			Logs:       g.logs(),
+			GasUsed:    g.gas(),
+		}, nil)
+	}
+}
+
+func (g receiptGenerator) receiptError() error {
+	switch g.rnd.Intn(7) {
+	case 0:
+		return common.ErrOutOfGas
+	case 1:
+		return &common.TemplateNotFoundError{Addr: g.address()}
+	case 2:
+		return &common.AppNotFoundError{Addr: g.address()}
+	case 3:
+		return &common.CompilationFailedError{TemplateAddr: g.address(), AppAddr: g.address(), Msg: g.text(100)}
+	case 4:
+		return &common.InstantiationFailedError{TemplateAddr: g.address(), AppAddr: g.address(), Msg: g.text(100)}
+	case 5:
+		return &common.FunctionNotFoundError{TemplateAddr: g.address(), AppAddr: g.address(), Func: g.text(10)}
+	default:
+		return &common.FunctionFailedError{TemplateAddr: g.address(), AppAddr: g.address(), Func: g.text(10), Msg: g.text(100)}
+	}
+}
+
+// normalizeReceipt clears what the codec wasm JSON round trip doesn't preserve:
+// the version, gas amounts beyond float64 precision, and non-UTF-8 log data, which is
+// decoded lossily into replacement characters.
+func normalizeReceipt(v interface{}) interface{} {
+	normalizeLogs := func(logs []common.Log) {
+		for i := range logs {
+			if !utf8.Valid(logs[i].Data) || bytes.ContainsRune(logs[i].Data, utf8.RuneError) {
+				logs[i].Data = nil
+			}
+		}
+	}
+	normalizeGas := func(gas *uint64) {
+		if *gas > 1<<53 {
+			*gas = 0
+		}
+	}
+
+	switch r := v.(type) {
+	case *common.ReceiptDeployTemplate:
+		r.Version = 0
+		normalizeGas(&r.GasUsed)
+	case *common.ReceiptSpawnApp:
+		r.Version = 0
+		normalizeGas(&r.GasUsed)
+		normalizeLogs(r.Logs)
+	case *common.ReceiptExecApp:
+		r.Version = 0
+		normalizeGas(&r.GasUsed)
+		normalizeLogs(r.Logs)
+	}
+
+	return v
+}
+
+func isReceiptError(err error) bool {
+	switch err.(type) {
+	case *common.TemplateNotFoundError, *common.AppNotFoundError,
+		*common.CompilationFailedError, *common.InstantiationFailedError,
+		*common.FunctionNotFoundError, *common.FunctionFailedError:
+		return true
+	default:
+		return errors.Is(err, common.ErrOutOfGas)
+	}
+}
+
+func receiptGasUsed(v interface{}) uint64 {
+	switch r := v.(type) {
+	case *common.ReceiptDeployTemplate:
+		return r.GasUsed
+	case *common.ReceiptSpawnApp:
+		return r.GasUsed
+	case *common.ReceiptExecApp:
+		return r.GasUsed
+	default:
+		return 0
+	}
+}
+
+func TestDecodeReceipt(t *testing.T) {
+	req := require.New(t)
+
+	raw, err := hex.DecodeString("0001bc213ffe5f285adf9b2df9975a98a8f3b8106bf7a02fda0000")
+	req.NoError(err)
+
+	v, err := decodeReceipt(raw)
+	req.NoError(err)
+	req.Equal(&common.ReceiptDeployTemplate{
+		Success:      true,
+		TemplateAddr: common.BytesToAddress(mustDecodeHex(t, "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7")),
+		GasUsed:      196000,
+	}, v)
+
+	exec := &common.ReceiptExecApp{
+		Success:    true,
+		Version:    9,
+		NewState:   make([]byte, stateSize),
+		Returndata: []byte{1, 2, 3},
+		Logs:       []common.Log{{Code: 1, Data: []byte{0xff, 0x00}}, {Code: 2, Data: []byte{}}},
+		GasUsed:    1 << 60,
+	}
+	v, err = decodeReceipt(encodeReceipt(exec, nil))
+	req.NoError(err)
+	req.Equal(exec, v)
+}
+
+func TestDecodeReceipt_Errors(t *testing.T) {
+	req := require.New(t)
+
+	templateAddr := common.BytesToAddress(mustDecodeHex(t, "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7"))
+	appAddr := common.BytesToAddress(mustDecodeHex(t, "a02fda0000000000000000000000000000000001"))
+
+	_, err := decodeReceipt(encodeReceiptError(receiptTypeExecApp, common.ErrOutOfGas, nil))
+	req.True(errors.Is(err, common.ErrOutOfGas))
+
+	receiptErrs := []error{
+		&common.TemplateNotFoundError{Addr: templateAddr},
+		&common.AppNotFoundError{Addr: appAddr},
+		&common.CompilationFailedError{TemplateAddr: templateAddr, AppAddr: appAddr, Msg: "Mayday"},
+		&common.InstantiationFailedError{TemplateAddr: templateAddr, AppAddr: appAddr, Msg: "Mayday"},
+		&common.FunctionNotFoundError{TemplateAddr: templateAddr, AppAddr: appAddr, Func: "run"},
+		&common.FunctionFailedError{TemplateAddr: templateAddr, AppAddr: appAddr, Func: "run", Msg: "Mayday"},
+	}
+	for _, receiptErr := range receiptErrs {
+		logs := []common.Log{{Code: 1, Data: []byte("hello")}}
+		_, err := decodeReceipt(encodeReceiptError(receiptTypeSpawnApp, receiptErr, logs))
+		req.Equal(receiptErr, err)
+	}
+}
+
+func TestDecodeReceipt_Invalid(t *testing.T) {
+	cases := []struct {
+		hex string
+		err string
+	}{
+		{"", "invalid receipt: invalid `type` field; unexpected end of data"},
+		{"0301", "invalid receipt: invalid receipt type: 3"},
+		{"00", "invalid deploy-template receipt: invalid `version` field; unexpected end of data"},
+		{"00888888888801", "invalid deploy-template receipt: invalid `version` field; version exceeds 10 nibbles"},
+		{"0002", "invalid deploy-template receipt: invalid `success` field; invalid bool: 2"},
+		{"0001bc21", "invalid deploy-template receipt: invalid `addr` field; unexpected end of data"},
+		{"0001bc213ffe5f285adf9b2df9975a98a8f3b8106bf70000",
+			"invalid deploy-template receipt: invalid `gas_used` field; invalid gas prefix: 0000"},
+		{"0001bc213ffe5f285adf9b2df9975a98a8f3b8106bf7a02f",
+			"invalid deploy-template receipt: invalid `gas_used` field; unexpected end of data"},
+		{"0001bc213ffe5f285adf9b2df9975a98a8f3b8106bf7a02fda0010",
+			"invalid deploy-template receipt: invalid `logs` field; unexpected end of data"},
+		{"0201" + "11111111111111111111111111111111", "invalid exec-app receipt: invalid `new_state` field; unexpected end of data"},
+		{"0000", "invalid receipt: invalid `err_type` field; unexpected end of data"},
+		{"000080", "invalid receipt: invalid error type: 8"},
+		{"0000100bc21", "invalid template-not-found error receipt: invalid `template_addr` field; unexpected end of data"},
+		{"0000500" + "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7" + "0102030405060708090a0b0c0d0e0f1011121314" + "0",
+			"invalid function-not-found error receipt: invalid `func` field; empty string"},
+		{"0000500" + "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7" + "0102030405060708090a0b0c0d0e0f1011121314" + "2fffe",
+			"invalid function-not-found error receipt: invalid `func` field; invalid UTF-8 string"},
+	}
+
+	for _, c := range cases {
+		raw := c.hex
+		if len(raw)%2 == 1 {
+			raw += "0"
+		}
+
+		_, err := decodeReceipt(mustDecodeHex(t, raw))
+		require.EqualError(t, err, c.err, c.hex)
+	}
+}
+
+// TestDecodeReceipt_Differential verifies that the native decoder agrees with the codec wasm.
+func TestDecodeReceipt_Differential(t *testing.T) {
+	g := receiptGenerator{rnd: rand.New(rand.NewSource(1))}
+	c := mustDefaultCodec(t)
+
+	for i := 0; i < 1000; i++ {
+		raw := g.receipt()
+
+		expected, expectedErr := c.decodeReceiptWasm(raw)
+		actual, err := decodeReceipt(raw)
+		require.Equal(t, expectedErr, err, "%x", raw)
+		require.Equal(t, expected, normalizeReceipt(actual), "%x", raw)
+	}
+}
+
+// TestDecodeReceipt_DifferentialFuzz mutates valid receipts randomly, and verifies that
+// the native decoder succeeds whenever the codec wasm does, with the same result.
+func TestDecodeReceipt_DifferentialFuzz(t *testing.T) {
+	g := receiptGenerator{rnd: rand.New(rand.NewSource(1))}
+	c := mustDefaultCodec(t)
+
+	for i := 0; i < 1000; i++ {
+		raw := g.receipt()
+		switch g.rnd.Intn(3) {
+		case 0:
+			raw = raw[:g.rnd.Intn(len(raw))]
+		default:
+			raw[g.rnd.Intn(len(raw))] = byte(g.rnd.Intn(256))
+		}
+
+		expected, expectedErr := c.decodeReceiptWasm(raw)
+
+		var actual interface{}
+		var err error
+		require.NotPanics(t, func() { actual, err = decodeReceipt(raw) }, "%x", raw)
+
+		if expectedErr != nil {
+			if isReceiptError(expectedErr) {
+				require.Equal(t, expectedErr, err, "%x", raw)
+			} else if receiptGasUsed(actual) <= math.MaxInt64 {
+				// The codec wasm fails to decode gas amounts beyond int64 range.
+				require.Error(t, err, "%x", raw)
+			}
+			continue
+		}
+		require.NoError(t, err, "%x", raw)
+		require.Equal(t, normalizeReceipt(expected), normalizeReceipt(actual), "%x", raw)
+	}
+}
+
+func BenchmarkDecodeReceipt(b *testing.B) {
+	g := receiptGenerator{rnd: rand.New(rand.NewSource(1))}
+	receipts := make([][]byte, 100)
+	for i := range receipts {
+		receipts[i] = g.receipt()
+	}
+
+	b.Run("native", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			decodeReceipt(receipts[i%len(receipts)])
+		}
+	})
+
+	b.Run("wasm", func(b *testing.B) {
+		c := mustDefaultCodec(b)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			c.decodeReceiptWasm(receipts[i%len(receipts)])
+		}
+	})
+}
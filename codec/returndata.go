@@ -0,0 +1,193 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"go-svm/common"
+	"reflect"
+)
+
+var (
+	abiValueType = reflect.TypeOf((*ABIValue)(nil)).Elem()
+	addressType  = reflect.TypeOf(common.Address{})
+)
+
+// DecodeReturndataValues decodes raw returndata, as encoded by an app's `svm_sdk::traits::Encoder`, natively.
+// Arrays, including byte arrays, are decoded as `Array` values.
+func DecodeReturndataValues(rawReturndata []byte) ([]ABIValue, error) {
+	d := &abiDecoder{data: rawReturndata}
+
+	values := []ABIValue{}
+	for !d.done() {
+		v, err := d.value(0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid returndata value #%v: %v", len(values), err)
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// DecodeReturndataInto decodes raw returndata into `out`, which must be a non-nil pointer.
+//
+// Returndata which holds a single value is decoded into `out` as is, while multiple values
+// are decoded as if they were an array. Arrays are decoded into slices, arrays,
+// or structs, whose exported fields receive the array items in order.
+// Integers are decoded into any Go integer type which can hold their value,
+// and addresses are decoded into `common.Address` or `codec.Address`.
+//
+// For example, the `[u32]` returndata of the counter example's `counter_add` can be
+// decoded into either `[]uint32`, `[2]uint32`, or `struct{ Old, New uint32 }`.
+func DecodeReturndataInto(rawReturndata []byte, out interface{}) error {
+	ptr := reflect.ValueOf(out)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("invalid output; expected: a non-nil pointer, given: %T", out)
+	}
+
+	values, err := DecodeReturndataValues(rawReturndata)
+	if err != nil {
+		return err
+	}
+
+	var v ABIValue = Array(values)
+	if len(values) == 1 {
+		v = values[0]
+	}
+
+	if err := assignABIValue(ptr.Elem(), v); err != nil {
+		return fmt.Errorf("invalid returndata: %v", err)
+	}
+
+	return nil
+}
+
+// assignABIValue assigns an ABI value to `dst`, converting it to `dst`'s type.
+func assignABIValue(dst reflect.Value, v ABIValue) error {
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assignABIValue(dst.Elem(), v)
+	}
+
+	if dst.Kind() == reflect.Interface && abiValueType.AssignableTo(dst.Type()) {
+		dst.Set(reflect.ValueOf(v))
+		return nil
+	}
+
+	src := reflect.ValueOf(v)
+	switch v := v.(type) {
+	case Bool:
+		if dst.Kind() == reflect.Bool {
+			dst.SetBool(bool(v))
+			return nil
+		}
+	case I8, I16, I32, I64:
+		if err := assignInt(dst, src.Int()); !errors.Is(err, errTypeMismatch) {
+			return err
+		}
+	case U8, U16, U32, U64, Amount:
+		if err := assignUint(dst, src.Uint()); !errors.Is(err, errTypeMismatch) {
+			return err
+		}
+	case Address:
+		if addressType.ConvertibleTo(dst.Type()) {
+			dst.Set(src.Convert(dst.Type()))
+			return nil
+		}
+	case Array:
+		if err := assignArray(dst, v); !errors.Is(err, errTypeMismatch) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("cannot decode %v into %v", v.abiType(), dst.Type())
+}
+
+var errTypeMismatch = errors.New("type mismatch")
+
+func assignInt(dst reflect.Value, i int64) error {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if dst.OverflowInt(i) {
+			return fmt.Errorf("value %v overflows %v", i, dst.Type())
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i < 0 || dst.OverflowUint(uint64(i)) {
+			return fmt.Errorf("value %v overflows %v", i, dst.Type())
+		}
+		dst.SetUint(uint64(i))
+	default:
+		return errTypeMismatch
+	}
+
+	return nil
+}
+
+func assignUint(dst reflect.Value, u uint64) error {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if int64(u) < 0 || dst.OverflowInt(int64(u)) {
+			return fmt.Errorf("value %v overflows %v", u, dst.Type())
+		}
+		dst.SetInt(int64(u))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if dst.OverflowUint(u) {
+			return fmt.Errorf("value %v overflows %v", u, dst.Type())
+		}
+		dst.SetUint(u)
+	default:
+		return errTypeMismatch
+	}
+
+	return nil
+}
+
+func assignArray(dst reflect.Value, arr Array) error {
+	switch dst.Kind() {
+	case reflect.Slice:
+		dst.Set(reflect.MakeSlice(dst.Type(), len(arr), len(arr)))
+	case reflect.Array:
+		if dst.Len() != len(arr) {
+			return fmt.Errorf("cannot decode an array of %v items into %v", len(arr), dst.Type())
+		}
+	case reflect.Struct:
+		fields := exportedFields(dst.Type())
+		if len(fields) != len(arr) {
+			return fmt.Errorf("cannot decode an array of %v items into %v, which has %v exported fields",
+				len(arr), dst.Type(), len(fields))
+		}
+
+		for i, item := range arr {
+			field := dst.Type().Field(fields[i])
+			if err := assignABIValue(dst.Field(fields[i]), item); err != nil {
+				return fmt.Errorf("field `%v`: %v", field.Name, err)
+			}
+		}
+		return nil
+	default:
+		return errTypeMismatch
+	}
+
+	for i, item := range arr {
+		if err := assignABIValue(dst.Index(i), item); err != nil {
+			return fmt.Errorf("item #%v: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// exportedFields returns the indices of a struct's exported fields.
+func exportedFields(t reflect.Type) []int {
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			fields = append(fields, i)
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,218 @@
+package codec
+
+import (
+	"github.com/stretchr/testify/require"
+	"go-svm/common"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDecodeReturndataValues(t *testing.T) {
+	cases := []struct {
+		hex    string
+		values []ABIValue
+	}{
+		{"", []ABIValue{}},
+		{"2643014302", []ABIValue{Array{U32(1), U32(2)}}},
+		{"1000", []ABIValue{Bool(true), Bool(false)}},
+		{"02ff12ff", []ABIValue{I8(-1), U8(255)}},
+		{"32fffe22c852012c4205", []ABIValue{I16(-2), I16(200), U16(300), U16(5)}},
+		{"33fffffffb03c813ffff", []ABIValue{I32(-5), I32(200), I32(65535)}},
+		{"74ffffffffffffffff" + "0480" + "55010000000000" + "0500", []ABIValue{I64(-1), I64(128), U64(1 << 40), U64(0)}},
+		{"0105", []ABIValue{Amount(5)}},
+		{"40bc213ffe5f285adf9b2df9975a98a8f3b8106bf7", []ABIValue{Address(txAddr)}},
+		{"06" + "7600", []ABIValue{Array{}, Array{}}},
+		{"2606161201", []ABIValue{Array{Array{}, Array{U8(1)}}}},
+		{"7607" + "1200120112021203120412051206", []ABIValue{Array{U8(0), U8(1), U8(2), U8(3), U8(4), U8(5), U8(6)}}},
+	}
+
+	for _, c := range cases {
+		values, err := DecodeReturndataValues(mustDecodeHex(t, c.hex))
+		require.NoError(t, err, c.hex)
+		require.Equal(t, c.values, values, c.hex)
+	}
+}
+
+func TestDecodeReturndataValues_Invalid(t *testing.T) {
+	cases := []struct {
+		hex string
+		err string
+	}{
+		{"43", "invalid returndata value #0: unexpected end of data"},
+		{"430120", "invalid returndata value #1: invalid marker: 0x20"},
+		{"83000000000000000000", "invalid returndata value #0: invalid integer size: 5 bytes"},
+		{"620000", "invalid returndata value #0: invalid marker: 0x62"},
+		{"850000000000000000000000", "invalid returndata value #0: invalid integer size: 9 bytes"},
+		{"40bc21", "invalid returndata value #0: unexpected end of data"},
+		{"76", "invalid returndata value #0: unexpected end of data"},
+		{"3610", "invalid returndata value #0: array item #1: unexpected end of data"},
+		{"16161612", "invalid returndata value #0: array item #0: array item #0: array item #0: unexpected end of data"},
+	}
+
+	for _, c := range cases {
+		_, err := DecodeReturndataValues(mustDecodeHex(t, c.hex))
+		require.EqualError(t, err, c.err, c.hex)
+	}
+
+	deep := make([]byte, maxArrayDepth+1)
+	for i := range deep {
+		deep[i] = 0x16
+	}
+	_, err := DecodeReturndataValues(deep)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "arrays are nested too deep; max depth: 32")
+}
+
+// normalizeABIValue replaces `Bytes` with the `Array` of `U8` it's decoded as.
+func normalizeABIValue(v ABIValue) ABIValue {
+	switch v := v.(type) {
+	case Bytes:
+		arr := make(Array, len(v))
+		for i, b := range v {
+			arr[i] = U8(b)
+		}
+		return arr
+	case Array:
+		arr := make(Array, len(v))
+		for i, item := range v {
+			arr[i] = normalizeABIValue(item)
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+func TestDecodeReturndataValues_RoundTrip(t *testing.T) {
+	g := abiGenerator{rnd: rand.New(rand.NewSource(1))}
+
+	for i := 0; i < 1000; i++ {
+		values := make([]ABIValue, 1+g.rnd.Intn(4))
+		for j := range values {
+			v, _ := g.value(2)
+			values[j] = normalizeABIValue(v)
+		}
+
+		raw, err := EncodeCallDataValues(values...)
+		require.NoError(t, err)
+
+		decoded, err := DecodeReturndataValues(raw)
+		require.NoError(t, err, "%x", raw)
+		require.Equal(t, values, decoded, "%x", raw)
+	}
+}
+
+// TestDecodeReturndataValues_DifferentialFuzz verifies that the native decoder
+// succeeds exactly when the codec wasm does, given random data.
+func TestDecodeReturndataValues_DifferentialFuzz(t *testing.T) {
+	g := abiGenerator{rnd: rand.New(rand.NewSource(1))}
+
+	for i := 0; i < 1000; i++ {
+		v, _ := g.value(2)
+		raw, err := EncodeCallDataValues(v)
+		require.NoError(t, err)
+
+		switch g.rnd.Intn(3) {
+		case 0:
+			raw = raw[:g.rnd.Intn(len(raw))]
+		case 1:
+			raw[g.rnd.Intn(len(raw))] = byte(g.rnd.Intn(256))
+		default:
+			raw = randomBytes(g.rnd, g.rnd.Intn(16))
+		}
+
+		_, expectedErr := DecodeReturndata(raw)
+
+		var err2 error
+		require.NotPanics(t, func() { _, err2 = DecodeReturndataValues(raw) }, "%x", raw)
+		require.Equal(t, expectedErr == nil, err2 == nil, "%x: %v", raw, err2)
+	}
+}
+
+func TestDecodeReturndataInto(t *testing.T) {
+	req := require.New(t)
+
+	// `counter_add` returns the counter's old and new values as a `Vec<u32>`.
+	raw, err := EncodeCallDataValues(Array{U32(10), U32(15)})
+	req.NoError(err)
+
+	var slice []uint32
+	req.NoError(DecodeReturndataInto(raw, &slice))
+	req.Equal([]uint32{10, 15}, slice)
+
+	var array [2]uint32
+	req.NoError(DecodeReturndataInto(raw, &array))
+	req.Equal([2]uint32{10, 15}, array)
+
+	var counter struct {
+		Old, New uint32
+		internal int
+	}
+	req.NoError(DecodeReturndataInto(raw, &counter))
+	req.Equal(uint32(10), counter.Old)
+	req.Equal(uint32(15), counter.New)
+
+	var ints []int64
+	req.NoError(DecodeReturndataInto(raw, &ints))
+	req.Equal([]int64{10, 15}, ints)
+
+	var u uint32
+	req.NoError(DecodeReturndataInto([]byte{abiU32, 7}, &u))
+	req.Equal(uint32(7), u)
+
+	// Multiple values are decoded as if they were an array.
+	raw, err = EncodeCallDataValues(Bool(true), Address(txAddr), Array{Bytes{1, 2}, Bytes{}}, I16(-3), Amount(math.MaxUint64))
+	req.NoError(err)
+
+	var out struct {
+		Ok     bool
+		Owner  common.Address
+		Blobs  [][]byte
+		Delta  *int
+		Amount interface{}
+	}
+	req.NoError(DecodeReturndataInto(raw, &out))
+	req.True(out.Ok)
+	req.Equal(txAddr, out.Owner)
+	req.Equal([][]byte{{1, 2}, {}}, out.Blobs)
+	req.Equal(-3, *out.Delta)
+	req.Equal(Amount(math.MaxUint64), out.Amount)
+
+	var values []interface{}
+	req.NoError(DecodeReturndataInto(raw, &values))
+	req.Len(values, 5)
+	req.Equal(Address(txAddr), values[1])
+
+	var addrs [1][common.AddressSize]byte
+	req.NoError(DecodeReturndataInto(mustDecodeHex(t, "1640bc213ffe5f285adf9b2df9975a98a8f3b8106bf7"), &addrs))
+	req.Equal([common.AddressSize]byte(txAddr), addrs[0])
+}
+
+func TestDecodeReturndataInto_Invalid(t *testing.T) {
+	raw, err := EncodeCallDataValues(Array{U32(10), U32(300)})
+	require.NoError(t, err)
+
+	var slice []uint32
+	require.EqualError(t, DecodeReturndataInto(raw, slice), "invalid output; expected: a non-nil pointer, given: []uint32")
+	require.EqualError(t, DecodeReturndataInto(raw, nil), "invalid output; expected: a non-nil pointer, given: <nil>")
+
+	var bytes []uint8
+	require.EqualError(t, DecodeReturndataInto(raw, &bytes), "invalid returndata: item #1: value 300 overflows uint8")
+
+	var array [3]uint32
+	require.EqualError(t, DecodeReturndataInto(raw, &array),
+		"invalid returndata: cannot decode an array of 2 items into [3]uint32")
+
+	var counter struct{ Old uint32 }
+	require.EqualError(t, DecodeReturndataInto(raw, &counter),
+		"invalid returndata: cannot decode an array of 2 items into struct { Old uint32 }, which has 1 exported fields")
+
+	var named struct{ Old, New string }
+	require.EqualError(t, DecodeReturndataInto(raw, &named), "invalid returndata: field `Old`: cannot decode u32 into string")
+
+	var u uint32
+	require.EqualError(t, DecodeReturndataInto(raw, &u), "invalid returndata: cannot decode [u32] into uint32")
+	require.EqualError(t, DecodeReturndataInto([]byte{abiI8, 0xff}, &u), "invalid returndata: value -1 overflows uint32")
+	require.EqualError(t, DecodeReturndataInto([]byte{0x43}, &u), "invalid returndata value #0: unexpected end of data")
+}
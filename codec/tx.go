@@ -0,0 +1,82 @@
+package codec
+
+import (
+	"fmt"
+)
+
+// DecodeTxDeployTemplate decodes a raw deploy-template transaction, as encoded by `EncodeTxDeployTemplate`.
+// Raw transactions are nibble-aligned, and are laid out as follows:
+//
+//	+-----------+----------+------------+--------+-------------+-------------+
+//	|  version  |  name    |  code size |  code  |  var count  |  var sizes  |
+//	| (nibbles) | (string) |  (4 bytes) |        | (varuint14) | (varuint14) |
+//	+-----------+----------+------------+--------+-------------+-------------+
+//
+// Strings are encoded as their length (varuint14), followed by their UTF-8 bytes.
+func DecodeTxDeployTemplate(rawTx []byte) (*TxDeployTemplate, error) {
+	d := &nibbleDecoder{data: rawTx}
+
+	tx := &TxDeployTemplate{
+		Version: d.version("version"),
+		Name:    d.string("name"),
+		Code:    d.bytes32("code"),
+	}
+
+	count := d.varuint14("data")
+	if d.err == nil {
+		tx.DataLayout = make([]uint32, count)
+		for i := range tx.DataLayout {
+			tx.DataLayout[i] = uint32(d.varuint14("data"))
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid deploy-template transaction: %v", d.err)
+	}
+
+	return tx, nil
+}
+
+// DecodeTxSpawnApp decodes a raw spawn-app transaction, as encoded by `EncodeTxSpawnApp`:
+//
+//	+-----------+------------+----------+-----------+---------------+
+//	|  version  |  template  |  name    | ctor name |  calldata     |
+//	| (nibbles) | (20 bytes) | (string) | (string)  | (size, bytes) |
+//	+-----------+------------+----------+-----------+---------------+
+func DecodeTxSpawnApp(rawTx []byte) (*TxSpawnApp, error) {
+	d := &nibbleDecoder{data: rawTx}
+
+	tx := &TxSpawnApp{
+		Version:      d.version("version"),
+		TemplateAddr: d.address("template"),
+		Name:         d.string("name"),
+		CtorName:     d.string("ctor_name"),
+		Calldata:     d.bytes("calldata", d.varuint14("calldata")),
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid spawn-app transaction: %v", d.err)
+	}
+
+	return tx, nil
+}
+
+// DecodeTxExecApp decodes a raw exec-app transaction, as encoded by `EncodeTxExecApp`:
+//
+//	+-----------+------------+-----------+---------------+
+//	|  version  |  app       | func name |  calldata     |
+//	| (nibbles) | (20 bytes) | (string)  | (size, bytes) |
+//	+-----------+------------+-----------+---------------+
+func DecodeTxExecApp(rawTx []byte) (*TxExecApp, error) {
+	d := &nibbleDecoder{data: rawTx}
+
+	tx := &TxExecApp{
+		Version:  d.version("version"),
+		AppAddr:  d.address("app"),
+		FuncName: d.string("func_name"),
+		Calldata: d.bytes("calldata", d.varuint14("calldata")),
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid exec-app transaction: %v", d.err)
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,160 @@
+package codec
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"go-svm/common"
+	"math/rand"
+	"testing"
+)
+
+var txAddr = common.BytesToAddress([]byte{
+	0xbc, 0x21, 0x3f, 0xfe, 0x5f, 0x28, 0x5a, 0xdf, 0x9b, 0x2d,
+	0xf9, 0x97, 0x5a, 0x98, 0xa8, 0xf3, 0xb8, 0x10, 0x6b, 0xf7,
+})
+
+func encodeDataLayout(layout []uint32) []byte {
+	data := make([]byte, 4*len(layout))
+	for i, size := range layout {
+		binary.BigEndian.PutUint32(data[4*i:], size)
+	}
+	return data
+}
+
+func TestDecodeTxDeployTemplate(t *testing.T) {
+	req := require.New(t)
+
+	// As encoded by `wasm_deploy_template`.
+	tx, err := DecodeTxDeployTemplate(mustDecodeHex(t, "016300000001aa244540"))
+	req.NoError(err)
+	req.Equal(&TxDeployTemplate{
+		Name:       "c",
+		Code:       []byte{0xaa},
+		DataLayout: []uint32{4, 20},
+	}, tx)
+
+	tx, err = DecodeTxDeployTemplate(mustDecodeHex(t, "01630000000000"))
+	req.NoError(err)
+	req.Equal(&TxDeployTemplate{Name: "c", Code: []byte{}, DataLayout: []uint32{}}, tx)
+}
+
+func TestDecodeTxSpawnApp(t *testing.T) {
+	req := require.New(t)
+
+	// As encoded by `wasm_encode_spawn_app`.
+	tx, err := DecodeTxSpawnApp(mustDecodeHex(t,
+		"0bc213ffe5f285adf9b2df9975a98a8f3b8106bf747636f756e7465724a696e697469616c697a6520102"))
+	req.NoError(err)
+	req.Equal(&TxSpawnApp{
+		TemplateAddr: txAddr,
+		Name:         "counter",
+		CtorName:     "initialize",
+		Calldata:     []byte{0x01, 0x02},
+	}, tx)
+}
+
+func TestDecodeTxExecApp(t *testing.T) {
+	req := require.New(t)
+
+	// As encoded by `wasm_encode_exec_app`.
+	tx, err := DecodeTxExecApp(mustDecodeHex(t,
+		"0bc213ffe5f285adf9b2df9975a98a8f3b8106bf74b636f756e7465725f61646420102"))
+	req.NoError(err)
+	req.Equal(&TxExecApp{
+		AppAddr:  txAddr,
+		FuncName: "counter_add",
+		Calldata: []byte{0x01, 0x02},
+	}, tx)
+}
+
+func TestDecodeTx_RoundTrip(t *testing.T) {
+	req := require.New(t)
+
+	deploy := &TxDeployTemplate{
+		Version:    9,
+		Name:       "counter",
+		Code:       []byte{0x00, 0x61, 0x73, 0x6d, 0x01},
+		DataLayout: []uint32{4, 20, 1000},
+	}
+	rawTx, err := EncodeTxDeployTemplate(deploy.Version, deploy.Name, deploy.Code, encodeDataLayout(deploy.DataLayout))
+	req.NoError(err)
+	tx, err := DecodeTxDeployTemplate(rawTx)
+	req.NoError(err)
+	req.Equal(deploy, tx)
+
+	spawn := &TxSpawnApp{
+		Version:      1,
+		TemplateAddr: txAddr,
+		Name:         "my-counter",
+		CtorName:     "initialize",
+		Calldata:     []byte{0x10, 0x20, 0x30},
+	}
+	rawTx, err = EncodeTxSpawnApp(spawn.Version, spawn.TemplateAddr[:], spawn.Name, spawn.CtorName, spawn.Calldata)
+	req.NoError(err)
+	spawnTx, err := DecodeTxSpawnApp(rawTx)
+	req.NoError(err)
+	req.Equal(spawn, spawnTx)
+
+	exec := &TxExecApp{
+		Version:  100,
+		AppAddr:  txAddr,
+		FuncName: "counter_add",
+		Calldata: make([]byte, 300),
+	}
+	rawTx, err = EncodeTxExecApp(exec.Version, exec.AppAddr[:], exec.FuncName, exec.Calldata)
+	req.NoError(err)
+	execTx, err := DecodeTxExecApp(rawTx)
+	req.NoError(err)
+	req.Equal(exec, execTx)
+}
+
+func TestDecodeTx_Invalid(t *testing.T) {
+	cases := []struct {
+		decode func([]byte) (interface{}, error)
+		hex    string
+		err    string
+	}{
+		{decodeTxDeployTemplate, "", "invalid deploy-template transaction: invalid `version` field; unexpected end of data"},
+		{decodeTxDeployTemplate, "00", "invalid deploy-template transaction: invalid `name` field; empty string"},
+		{decodeTxDeployTemplate, "0163000000", "invalid deploy-template transaction: invalid `code` field; unexpected end of data"},
+		{decodeTxDeployTemplate, "016300000002aa", "invalid deploy-template transaction: invalid `code` field; unexpected end of data"},
+		{decodeTxDeployTemplate, "0163ffffffffaa", "invalid deploy-template transaction: invalid `code` field; unexpected end of data"},
+		{decodeTxDeployTemplate, "016380000000aa", "invalid deploy-template transaction: invalid `code` field; unexpected end of data"},
+		{decodeTxDeployTemplate, "016300000001aa24", "invalid deploy-template transaction: invalid `data` field; unexpected end of data"},
+		{decodeTxSpawnApp, "0bc21", "invalid spawn-app transaction: invalid `template` field; unexpected end of data"},
+		{decodeTxSpawnApp, "0bc213ffe5f285adf9b2df9975a98a8f3b8106bf7" + "163" + "0",
+			"invalid spawn-app transaction: invalid `ctor_name` field; empty string"},
+		{decodeTxSpawnApp, "0bc213ffe5f285adf9b2df9975a98a8f3b8106bf7" + "163" + "163" + "3aa",
+			"invalid spawn-app transaction: invalid `calldata` field; unexpected end of data"},
+		{decodeTxExecApp, "0bc213ffe5f285adf9b2df9975a98a8f3b8106bf7" + "1ff",
+			"invalid exec-app transaction: invalid `func_name` field; invalid UTF-8 string"},
+	}
+
+	for _, c := range cases {
+		raw := c.hex
+		if len(raw)%2 == 1 {
+			raw += "0"
+		}
+
+		_, err := c.decode(mustDecodeHex(t, raw))
+		require.EqualError(t, err, c.err, c.hex)
+	}
+}
+
+func TestDecodeTx_Fuzz(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10000; i++ {
+		raw := randomBytes(rnd, rnd.Intn(64))
+
+		require.NotPanics(t, func() {
+			DecodeTxDeployTemplate(raw)
+			DecodeTxSpawnApp(raw)
+			DecodeTxExecApp(raw)
+		}, "%x", raw)
+	}
+}
+
+func decodeTxDeployTemplate(rawTx []byte) (interface{}, error) { return DecodeTxDeployTemplate(rawTx) }
+func decodeTxSpawnApp(rawTx []byte) (interface{}, error)       { return DecodeTxSpawnApp(rawTx) }
+func decodeTxExecApp(rawTx []byte) (interface{}, error)        { return DecodeTxExecApp(rawTx) }
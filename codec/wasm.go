@@ -0,0 +1,11 @@
+package codec
+
+import (
+	_ "embed"
+)
+
+// bundledWasm is the codec wasm module, which is bundled into the package,
+// so that programs using it don't depend on the module's file at runtime.
+//
+//go:embed svm_codec.wasm
+var bundledWasm []byte
@@ -1,17 +1,89 @@
 package common
 
 import (
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
 )
 
 const AddressSize = 20
 
+// ErrInvalidAddressChecksum is returned when parsing a mixed-case address whose letters' case
+// doesn't match its checksum; see `Address.Checksum`.
+var ErrInvalidAddressChecksum = errors.New("invalid address checksum")
+
 type Address [AddressSize]byte
 
+// ParseAddress parses an address from its hex encoding, with an optional `0x` prefix.
+// All-lowercase and all-uppercase addresses are accepted as is,
+// while mixed-case addresses must match their checksum.
+func ParseAddress(s string) (Address, error) {
+	var addr Address
+
+	h := s
+	if strings.HasPrefix(h, "0x") || strings.HasPrefix(h, "0X") {
+		h = h[2:]
+	}
+	if len(h) != 2*AddressSize {
+		return addr, fmt.Errorf("invalid address length; expected: %v hex characters, given: %v", 2*AddressSize, len(h))
+	}
+	if _, err := hex.Decode(addr[:], []byte(h)); err != nil {
+		return Address{}, fmt.Errorf("invalid address: %v", err)
+	}
+
+	if h != strings.ToLower(h) && h != strings.ToUpper(h) && h != addr.Checksum() {
+		return Address{}, ErrInvalidAddressChecksum
+	}
+
+	return addr, nil
+}
+
 func (addr Address) String() string {
 	return hex.EncodeToString(addr[:])
 }
 
+// Checksum returns the hex encoding of the address, in which the case of each letter encodes a checksum.
+// Similar to EIP-55, a letter is uppercased if the matching nibble of the SHA-256 hash
+// of the lowercase hex encoding is 8 or more.
+func (addr Address) Checksum() string {
+	h := []byte(addr.String())
+	hash := sha256.Sum256(h)
+
+	for i, c := range h {
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			h[i] = c - 'a' + 'A'
+		}
+	}
+
+	return string(h)
+}
+
+// MarshalText implements `encoding.TextMarshaler`, using the lowercase hex encoding.
+// It's also used for encoding addresses as JSON strings.
+func (addr Address) MarshalText() ([]byte, error) {
+	return []byte(addr.String()), nil
+}
+
+// UnmarshalText implements `encoding.TextUnmarshaler`, accepting any format `ParseAddress` does.
+// It's also used for decoding addresses from JSON strings.
+func (addr *Address) UnmarshalText(text []byte) error {
+	parsed, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+
+	*addr = parsed
+	return nil
+}
+
+// BytesToAddress converts bytes to an address, truncating or zero-padding them as needed.
+// Use `ParseAddress` for parsing addresses strictly.
 func BytesToAddress(b []byte) Address {
 	var addr Address
 	if len(b) <= AddressSize {
@@ -0,0 +1,133 @@
+package common
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const addrHex = "bc213ffe5f285adf9b2df9975a98a8f3b8106bf7"
+
+func TestParseAddress(t *testing.T) {
+	req := require.New(t)
+
+	expected := Address{
+		0xbc, 0x21, 0x3f, 0xfe, 0x5f, 0x28, 0x5a, 0xdf, 0x9b, 0x2d,
+		0xf9, 0x97, 0x5a, 0x98, 0xa8, 0xf3, 0xb8, 0x10, 0x6b, 0xf7,
+	}
+
+	for _, s := range []string{
+		addrHex,
+		"0x" + addrHex,
+		strings.ToUpper(addrHex),
+		"0x" + strings.ToUpper(addrHex),
+		expected.Checksum(),
+		"0x" + expected.Checksum(),
+	} {
+		addr, err := ParseAddress(s)
+		req.NoError(err, s)
+		req.Equal(expected, addr, s)
+	}
+}
+
+func TestParseAddress_Invalid(t *testing.T) {
+	cases := []struct {
+		s   string
+		err string
+	}{
+		{"", "invalid address length; expected: 40 hex characters, given: 0"},
+		{"0x", "invalid address length; expected: 40 hex characters, given: 0"},
+		{addrHex[2:], "invalid address length; expected: 40 hex characters, given: 38"},
+		{addrHex + "00", "invalid address length; expected: 40 hex characters, given: 42"},
+		{"zz" + addrHex[2:], "invalid address: encoding/hex: invalid byte: U+007A 'z'"},
+		{" " + addrHex[1:], "invalid address: encoding/hex: invalid byte: U+0020 ' '"},
+		{"Bc213ffe5f285adf9b2df9975a98a8f3b8106bf7", "invalid address checksum"},
+		{"0x0X" + addrHex, "invalid address length; expected: 40 hex characters, given: 42"},
+		{"0x0X" + addrHex[2:], "invalid address: encoding/hex: invalid byte: U+0058 'X'"},
+	}
+
+	for _, c := range cases {
+		_, err := ParseAddress(c.s)
+		require.EqualError(t, err, c.err, c.s)
+	}
+}
+
+func TestAddress_Checksum(t *testing.T) {
+	req := require.New(t)
+
+	addr, err := ParseAddress(addrHex)
+	req.NoError(err)
+
+	checksum := addr.Checksum()
+	req.Equal(addrHex, strings.ToLower(checksum))
+	req.NotEqual(addrHex, checksum)
+	req.Equal(checksum, addr.Checksum())
+
+	// Flipping the case of any single letter breaks the checksum.
+	for i, c := range checksum {
+		var flipped string
+		switch {
+		case c >= 'a' && c <= 'f':
+			flipped = checksum[:i] + strings.ToUpper(string(c)) + checksum[i+1:]
+		case c >= 'A' && c <= 'F':
+			flipped = checksum[:i] + strings.ToLower(string(c)) + checksum[i+1:]
+		default:
+			continue
+		}
+
+		if flipped == strings.ToLower(flipped) || flipped == strings.ToUpper(flipped) {
+			continue
+		}
+		_, err := ParseAddress(flipped)
+		req.Equal(ErrInvalidAddressChecksum, err, flipped)
+	}
+
+	// Addresses without letters have no case to encode a checksum in.
+	var digits Address
+	req.Equal(strings.Repeat("0", 2*AddressSize), digits.Checksum())
+}
+
+func TestAddress_Text(t *testing.T) {
+	req := require.New(t)
+
+	addr, err := ParseAddress(addrHex)
+	req.NoError(err)
+
+	text, err := addr.MarshalText()
+	req.NoError(err)
+	req.Equal(addrHex, string(text))
+
+	var decoded Address
+	req.NoError(decoded.UnmarshalText([]byte("0x" + addr.Checksum())))
+	req.Equal(addr, decoded)
+
+	req.EqualError(decoded.UnmarshalText([]byte("0x1234")), "invalid address length; expected: 40 hex characters, given: 4")
+	req.Equal(addr, decoded)
+}
+
+func TestAddress_JSON(t *testing.T) {
+	req := require.New(t)
+
+	addr, err := ParseAddress(addrHex)
+	req.NoError(err)
+
+	type config struct {
+		Template Address            `json:"template"`
+		Apps     []Address          `json:"apps"`
+		Names    map[Address]string `json:"names"`
+	}
+
+	b, err := json.Marshal(config{Template: addr, Apps: []Address{addr}, Names: map[Address]string{addr: "counter"}})
+	req.NoError(err)
+	req.Equal(`{"template":"`+addrHex+`","apps":["`+addrHex+`"],"names":{"`+addrHex+`":"counter"}}`, string(b))
+
+	var decoded config
+	req.NoError(json.Unmarshal(b, &decoded))
+	req.Equal(addr, decoded.Template)
+	req.Equal([]Address{addr}, decoded.Apps)
+	req.Equal(map[Address]string{addr: "counter"}, decoded.Names)
+
+	err = json.Unmarshal([]byte(`{"template":"0x1234"}`), &decoded)
+	req.EqualError(err, "invalid address length; expected: 40 hex characters, given: 4")
+}
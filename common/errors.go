@@ -0,0 +1,82 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOutOfGas is returned when a transaction execution ran out of gas.
+var ErrOutOfGas = errors.New("out of gas")
+
+// TemplateNotFoundError is returned when a transaction refers to a non-existing template.
+type TemplateNotFoundError struct {
+	Addr Address
+}
+
+// Error helps TemplateNotFoundError to implement the error interface.
+func (e *TemplateNotFoundError) Error() string {
+	return fmt.Sprintf("template not found; template address: %v", e.Addr)
+}
+
+// AppNotFoundError is returned when a transaction refers to a non-existing app.
+type AppNotFoundError struct {
+	Addr Address
+}
+
+// Error helps AppNotFoundError to implement the error interface.
+func (e *AppNotFoundError) Error() string {
+	return fmt.Sprintf("app not found; app address: %v", e.Addr)
+}
+
+// CompilationFailedError is returned when the template code of an app failed to compile.
+type CompilationFailedError struct {
+	TemplateAddr Address
+	AppAddr      Address
+	Msg          string
+}
+
+// Error helps CompilationFailedError to implement the error interface.
+func (e *CompilationFailedError) Error() string {
+	return fmt.Sprintf("compilation failed; template address: %v, app address: %v, msg: %v",
+		e.TemplateAddr, e.AppAddr, e.Msg)
+}
+
+// InstantiationFailedError is returned when an app failed to be instantiated.
+type InstantiationFailedError struct {
+	TemplateAddr Address
+	AppAddr      Address
+	Msg          string
+}
+
+// Error helps InstantiationFailedError to implement the error interface.
+func (e *InstantiationFailedError) Error() string {
+	return fmt.Sprintf("instantiation failed; template address: %v, app address: %v, msg: %v",
+		e.TemplateAddr, e.AppAddr, e.Msg)
+}
+
+// FunctionNotFoundError is returned when a transaction refers to a non-existing app function.
+type FunctionNotFoundError struct {
+	TemplateAddr Address
+	AppAddr      Address
+	Func         string
+}
+
+// Error helps FunctionNotFoundError to implement the error interface.
+func (e *FunctionNotFoundError) Error() string {
+	return fmt.Sprintf("function not found; template address: %v, app address: %v, func: %v",
+		e.TemplateAddr, e.AppAddr, e.Func)
+}
+
+// FunctionFailedError is returned when an app function execution failed.
+type FunctionFailedError struct {
+	TemplateAddr Address
+	AppAddr      Address
+	Func         string
+	Msg          string
+}
+
+// Error helps FunctionFailedError to implement the error interface.
+func (e *FunctionFailedError) Error() string {
+	return fmt.Sprintf("function failed; template address: %v, app address: %v, func: %v, msg: %v",
+		e.TemplateAddr, e.AppAddr, e.Func, e.Msg)
+}
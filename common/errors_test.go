@@ -0,0 +1,33 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	req := require.New(t)
+
+	templateAddr := BytesToAddress([]byte{0x01})
+	appAddr := BytesToAddress([]byte{0x02})
+
+	err := fmt.Errorf("exec failed: %w", ErrOutOfGas)
+	req.True(errors.Is(err, ErrOutOfGas))
+	req.EqualError(err, "exec failed: out of gas")
+
+	err = fmt.Errorf("exec failed: %w", &AppNotFoundError{Addr: appAddr})
+	var appNotFound *AppNotFoundError
+	req.True(errors.As(err, &appNotFound))
+	req.Equal(appAddr, appNotFound.Addr)
+	req.EqualError(err, "exec failed: app not found; app address: 0200000000000000000000000000000000000000")
+
+	err = &FunctionFailedError{TemplateAddr: templateAddr, AppAddr: appAddr, Func: "run", Msg: "Mayday"}
+	var templateNotFound *TemplateNotFoundError
+	req.False(errors.As(err, &templateNotFound))
+	var functionFailed *FunctionFailedError
+	req.True(errors.As(err, &functionFailed))
+	req.Equal("run", functionFailed.Func)
+	req.Equal("Mayday", functionFailed.Msg)
+}
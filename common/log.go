@@ -0,0 +1,43 @@
+package common
+
+import "fmt"
+
+// Log is a log entry emitted by an app during its execution.
+type Log struct {
+	// Code is the app-defined log code, usable for classifying events.
+	Code uint8
+
+	// Data is the raw data logged by the app.
+	Data []byte
+}
+
+func (l Log) String() string {
+	return fmt.Sprintf("(code: %v, msg: %s)", l.Code, l.Data)
+}
+
+// FilterLogs returns the logs whose code matches any of the given codes,
+// preserving their original order.
+func FilterLogs(logs []Log, codes ...uint8) []Log {
+	var filtered []Log
+	for _, l := range logs {
+		for _, code := range codes {
+			if l.Code == code {
+				filtered = append(filtered, l)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
+// GroupLogs groups the logs by their code, preserving their original order
+// within each group.
+func GroupLogs(logs []Log) map[uint8][]Log {
+	groups := make(map[uint8][]Log)
+	for _, l := range logs {
+		groups[l.Code] = append(groups[l.Code], l)
+	}
+
+	return groups
+}
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLog_String(t *testing.T) {
+	require.Equal(t, "(code: 100, msg: hello)", Log{Code: 100, Data: []byte("hello")}.String())
+}
+
+func TestFilterLogs(t *testing.T) {
+	req := require.New(t)
+
+	logs := []Log{
+		{Code: 1, Data: []byte("a")},
+		{Code: 2, Data: []byte("b")},
+		{Code: 1, Data: []byte("c")},
+		{Code: 3, Data: []byte("d")},
+	}
+
+	req.Equal([]Log{logs[0], logs[2]}, FilterLogs(logs, 1))
+	req.Equal([]Log{logs[1], logs[3]}, FilterLogs(logs, 3, 2))
+	req.Empty(FilterLogs(logs, 4))
+	req.Empty(FilterLogs(logs))
+	req.Empty(FilterLogs(nil, 1))
+}
+
+func TestGroupLogs(t *testing.T) {
+	logs := []Log{
+		{Code: 1, Data: []byte("a")},
+		{Code: 2, Data: []byte("b")},
+		{Code: 1, Data: []byte("c")},
+	}
+
+	require.Equal(t, map[uint8][]Log{
+		1: {logs[0], logs[2]},
+		2: {logs[1]},
+	}, GroupLogs(logs))
+}
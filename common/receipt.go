@@ -13,7 +13,7 @@ type ReceiptSpawnApp struct {
 	AppAddr    Address
 	State      []byte
 	Returndata []byte
-	Logs       []string
+	Logs       []Log
 	GasUsed    uint64
 }
 
@@ -22,6 +22,6 @@ type ReceiptExecApp struct {
 	Version    int
 	NewState   []byte
 	Returndata []byte
-	Logs       []string
+	Logs       []Log
 	GasUsed    uint64
 }
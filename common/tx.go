@@ -0,0 +1,49 @@
+package common
+
+// TxDeployTemplate is a decoded deploy-template transaction.
+type TxDeployTemplate struct {
+	// Version is the transaction format version.
+	Version int
+
+	// Name is the template's name.
+	Name string
+
+	// Code is the template's WebAssembly code.
+	Code []byte
+
+	// DataLayout holds the byte-size of each of the template's variables.
+	DataLayout []uint32
+}
+
+// TxSpawnApp is a decoded spawn-app transaction.
+type TxSpawnApp struct {
+	// Version is the transaction format version.
+	Version int
+
+	// TemplateAddr is the address of the template to spawn the app from.
+	TemplateAddr Address
+
+	// Name is the app's name.
+	Name string
+
+	// CtorName is the name of the constructor function, invoked when spawning the app.
+	CtorName string
+
+	// Calldata holds the constructor's ABI-encoded arguments.
+	Calldata []byte
+}
+
+// TxExecApp is a decoded exec-app transaction.
+type TxExecApp struct {
+	// Version is the transaction format version.
+	Version int
+
+	// AppAddr is the address of the app to execute.
+	AppAddr Address
+
+	// FuncName is the name of the app function to invoke.
+	FuncName string
+
+	// Calldata holds the function's ABI-encoded arguments.
+	Calldata []byte
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"go-svm/codec"
 	"go-svm/svm"
+	"go-svm/tx"
 	"io/ioutil"
 )
 
@@ -18,31 +19,15 @@ var counter int32 = initialValue
 func main() {
 	// Build imports.
 	imports, err := svm.NewImportsBuilder().
-		RegisterFunction(
-			"add",
-			svm.ValueTypes{svm.TypeI32, svm.TypeI32},
-			svm.ValueTypes{svm.TypeI32},
-			func(args []svm.Value) ([]svm.Value, error) {
-				a := args[0].ToI32()
-				b := args[1].ToI32()
-				fmt.Printf("`add` invoked by SVM; args: (%v, %v)\n", a, b)
-
-				res := a + b
-				return []svm.Value{svm.I32(res)}, nil
-			},
-		).RegisterFunction(
-		"mul",
-		svm.ValueTypes{svm.TypeI32, svm.TypeI32},
-		svm.ValueTypes{svm.TypeI32},
-		func(args []svm.Value) ([]svm.Value, error) {
-			a := args[0].ToI32()
-			b := args[1].ToI32()
+		RegisterGoFunc("add", func(a, b int32) int32 {
+			fmt.Printf("`add` invoked by SVM; args: (%v, %v)\n", a, b)
+			return a + b
+		}).
+		RegisterGoFunc("mul", func(a, b int32) int32 {
 			fmt.Printf("`mul` invoked by SVM; args: (%v, %v)\n", a, b)
-
-			res := a * b
-			return []svm.Value{svm.I32(res)}, nil
-		},
-	).Build()
+			return a * b
+		}).
+		Build()
 	noError(err)
 	defer imports.Free()
 
@@ -90,7 +75,6 @@ func main() {
 	println()
 	defer svmRuntime.Free()
 
-	version := 0
 	gasMetering := false
 	gasLimit := uint64(0)
 
@@ -98,20 +82,22 @@ func main() {
 	code, err := ioutil.ReadFile(templateFilename)
 	noError(err)
 	name := "name"
-	dataLayout := svm.DataLayout{4}
-	tx, err := codec.EncodeTxDeployTemplate(version, name, code, dataLayout.Encode())
+	// The template's only variable, `VAR_ID = 0`, holds the counter.
+	layout, err := svm.NewLayoutBuilder().Var("counter", svm.VarU32).Build()
+	noError(err)
+	rawTx, err := tx.NewDeploy().Name(name).Code(code).Layout(layout).Build()
 	noError(err)
 
 	// Deploy Template: validate tx.
 	// TODO: re-enable; temporarily disabled due to pending SVM issue.
-	//err = svm.ValidateTemplate(svmRuntime, tx)
+	//err = svm.ValidateTemplate(svmRuntime, rawTx)
 	//noError(err)
 
 	// Deploy Template.
 	author := svm.Address{}
 	receiptDeployTemplate, err := svm.DeployTemplate(
 		svmRuntime,
-		tx,
+		rawTx,
 		author,
 		gasMetering,
 		gasLimit,
@@ -121,29 +107,22 @@ func main() {
 	println()
 
 	// Spawn App: generate tx.
-	calldata, err := codec.EncodeCallData(
-		[]string{"u32"},
-		[]int{initialValue},
-	)
-	noError(err)
-	tx, err = codec.EncodeTxSpawnApp(
-		version,
-		receiptDeployTemplate.TemplateAddr[:],
-		name,
-		"initialize",
-		calldata,
-	)
+	rawTx, err = tx.NewSpawn(receiptDeployTemplate.TemplateAddr).
+		Name(name).
+		Ctor("initialize").
+		Args(codec.U32(initialValue)).
+		Build()
 	noError(err)
 
 	// Spawn App: validate tx.
 	creator := svm.Address{}
-	err = svm.ValidateApp(svmRuntime, tx)
+	err = svm.ValidateApp(svmRuntime, rawTx)
 	noError(err)
 
 	// Spawn App.
 	receiptSpawnApp, err := svm.SpawnApp(
 		svmRuntime,
-		tx,
+		rawTx,
 		creator,
 		gasMetering,
 		gasLimit,
@@ -155,53 +134,36 @@ func main() {
 	fmt.Printf("Decoded Returndata: %v\n\n", returndata)
 	println()
 
+	// The counter's functions return its old and new values.
+	var result struct{ Old, New uint32 }
+
 	// Exec App: generate tx.
-	calldata, err = codec.EncodeCallData(
-		[]string{"u32"},
-		[]int{5},
-	)
-	noError(err)
-	tx, err = codec.EncodeTxExecApp(
-		version,
-		receiptSpawnApp.AppAddr[:],
-		"counter_add",
-		calldata,
-	)
+	rawTx, err = tx.NewCall(receiptSpawnApp.AppAddr).Func("counter_add").Args(codec.U32(5)).Build()
 	noError(err)
-	_, err = svm.ValidateAppTx(svmRuntime, tx)
+	_, err = svm.ValidateAppTx(svmRuntime, rawTx)
 	noError(err)
 
 	// Exec App.
-	receiptExecApp, err := svm.ExecApp(svmRuntime, tx, receiptSpawnApp.State, gasMetering, gasLimit)
+	receiptExecApp, err := svm.ExecApp(svmRuntime, rawTx, receiptSpawnApp.State, gasMetering, gasLimit)
 	noError(err)
 	spew.Dump(receiptExecApp)
-	returndata, err = codec.DecodeReturndata(receiptExecApp.Returndata)
+	err = codec.DecodeReturndataInto(receiptExecApp.Returndata, &result)
 	noError(err)
-	fmt.Printf("Decoded Returndata: %v\n\n", returndata)
+	fmt.Printf("Decoded Returndata: %+v\n\n", result)
 
 	// Exec App: generate tx.
-	calldata, err = codec.EncodeCallData(
-		[]string{"u32"},
-		[]int{5},
-	)
-	noError(err)
-	tx, err = codec.EncodeTxExecApp(
-		version,
-		receiptSpawnApp.AppAddr[:],
-		"counter_mul",
-		calldata,
-	)
+	rawTx, err = tx.NewCall(receiptSpawnApp.AppAddr).Func("counter_mul").Args(codec.U32(5)).Build()
 	noError(err)
-	_, err = svm.ValidateAppTx(svmRuntime, tx)
+	_, err = svm.ValidateAppTx(svmRuntime, rawTx)
 	noError(err)
 
 	// Exec App.
-	receiptExecApp, err = svm.ExecApp(svmRuntime, tx, receiptSpawnApp.State, gasMetering, gasLimit)
+	receiptExecApp, err = svm.ExecApp(svmRuntime, rawTx, receiptSpawnApp.State, gasMetering, gasLimit)
 	noError(err)
 	spew.Dump(receiptExecApp)
-	returndata, err = codec.DecodeReturndata(receiptExecApp.Returndata)
+	err = codec.DecodeReturndataInto(receiptExecApp.Returndata, &result)
 	noError(err)
-	fmt.Printf("Decoded Returndata: %v\n\n", returndata)
+	fmt.Printf("Decoded Returndata: %+v\n\n", result)
 
 }
 
@@ -0,0 +1,96 @@
+package svm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ReadAppVar returns the raw value of the variable `varID` of the app at `appAddr`,
+// at the state `state`. The value is of size `KVValueSize`, and holds the variable's
+// value at its beginning. It is all zeros if the variable was never set.
+//
+// App storage is read through the runtime's FFI state KV, so the runtime must be built
+// with `WithStateKV_FFI`. Past states can only be read if the FFI state KV was created
+// by `NewStateKV_FFIFrom` with a HistoricalStateKV, such as InMemoryStateKV or FileStateKV.
+// Otherwise, `state` must be the current state.
+func ReadAppVar(runtime Runtime, appAddr Address, state []byte, varID uint32) ([]byte, error) {
+	get, err := runtime.stateReader(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read app storage: %v", err)
+	}
+
+	value, err := get(appVarKey(appAddr, varID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read app storage: %v", err)
+	}
+
+	switch len(value) {
+	case 0:
+		return make([]byte, KVValueSize), nil
+	case KVValueSize:
+		return value, nil
+	default:
+		return nil, fmt.Errorf("failed to read app storage: invalid value size; expected: %v, given: %v", KVValueSize, len(value))
+	}
+}
+
+// ReadAppVars reads all the variables of the app at `appAddr`, at the state `state`,
+// and decodes them by name according to the layout of the app's template (see `Layout.DecodeVars`).
+func ReadAppVars(runtime Runtime, appAddr Address, state []byte, layout Layout) (map[string]interface{}, error) {
+	sizes := make(map[uint32]uint32, len(layout))
+	for _, v := range layout {
+		sizes[v.ID] = v.Type.Size()
+	}
+
+	return layout.DecodeVars(func(varID uint32) ([]byte, error) {
+		raw, err := ReadAppVar(runtime, appAddr, state, varID)
+		if err != nil {
+			return nil, err
+		}
+
+		size := sizes[varID]
+		if size > KVValueSize {
+			return nil, fmt.Errorf("variable size exceeds %v bytes", KVValueSize)
+		}
+
+		return raw[:size], nil
+	})
+}
+
+// appVarKey returns the state KV key of an app's variable, which SVM derives
+// by appending the big-endian variable id to the app's address.
+func appVarKey(appAddr Address, varID uint32) []byte {
+	key := make([]byte, AddressSize+4)
+	copy(key, appAddr[:])
+	binary.BigEndian.PutUint32(key[AddressSize:], varID)
+
+	return key
+}
+
+// stateReader returns a reader of the values of the runtime's state KV at `state`.
+func (r Runtime) stateReader(state []byte) (func(key []byte) ([]byte, error), error) {
+	kv := r.kv
+	if kv == nil || kv._inner == nil {
+		return nil, errors.New("the runtime isn't backed by an FFI state KV")
+	}
+
+	if historical, ok := kv.backend.(HistoricalStateKV); ok {
+		return func(key []byte) ([]byte, error) {
+			return historical.GetAt(state, key)
+		}, nil
+	}
+
+	handlers := kvHandlersStore.get(kv.slot)
+	if handlers.get == nil || handlers.head == nil {
+		return nil, errors.New("the FFI state KV `get` and `head` handlers aren't registered")
+	}
+	if head := handlers.head(); !bytes.Equal(head, state) {
+		return nil, fmt.Errorf("state %x isn't the current state %x, and the FFI state KV can't read past states", state, head)
+	}
+
+	return func(key []byte) ([]byte, error) {
+		return handlers.get(key), nil
+	}, nil
+}
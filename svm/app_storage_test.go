@@ -0,0 +1,53 @@
+package svm
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAppVarKey(t *testing.T) {
+	appAddr := BytesToAddress(bytes.Repeat([]byte{0xaa}, AddressSize))
+
+	key := appVarKey(appAddr, 0x0102)
+	require.Equal(t, append(appAddr[:], 0x00, 0x00, 0x01, 0x02), key)
+}
+
+func TestReadAppVar_NoFFIStateKV(t *testing.T) {
+	_, err := ReadAppVar(Runtime{}, Address{}, make([]byte, StateSize), 0)
+	require.EqualError(t, err, "failed to read app storage: the runtime isn't backed by an FFI state KV")
+}
+
+func TestReadAppVar_CurrentStateOnly(t *testing.T) {
+	req := require.New(t)
+
+	m, kv := newMapKV(t)
+	defer kv.Free()
+
+	runtime := Runtime{kv: kv}
+	appAddr := BytesToAddress([]byte{1})
+	value := bytes.Repeat([]byte{0xbb}, KVValueSize)
+	m.data[string(appVarKey(appAddr, 1))] = value
+
+	raw, err := ReadAppVar(runtime, appAddr, m.state, 1)
+	req.NoError(err)
+	req.Equal(value, raw)
+
+	values, err := ReadAppVars(runtime, appAddr, m.state, Layout{
+		{ID: 0, Name: "enabled", Type: VarBool},
+		{ID: 1, Name: "hash", Type: VarBytes(4)},
+	})
+	req.NoError(err)
+	req.Equal(map[string]interface{}{"enabled": false, "hash": value[:4]}, values)
+
+	// Past states can't be read without a HistoricalStateKV.
+	past := bytes.Repeat([]byte{0xcc}, StateSize)
+	_, err = ReadAppVar(runtime, appAddr, past, 1)
+	req.EqualError(err, "failed to read app storage: state "+
+		"cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc isn't the current state "+
+		"0000000000000000000000000000000000000000000000000000000000000000, and the FFI state KV can't read past states")
+
+	kv.Free()
+	_, err = ReadAppVar(runtime, appAddr, m.state, 1)
+	req.EqualError(err, "failed to read app storage: the runtime isn't backed by an FFI state KV")
+}
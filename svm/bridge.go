@@ -7,6 +7,7 @@ package svm
 import "C"
 
 import (
+	"fmt"
 	"unsafe"
 )
 
@@ -40,6 +41,27 @@ func cSvmMemoryRuntimeCreate(runtime *unsafe.Pointer, kv, imports unsafe.Pointer
 	return nil
 }
 
+func cSvmRuntimeCreate(runtime *unsafe.Pointer, kvPath string, imports unsafe.Pointer) error {
+	cKVPath := bytesCloneToSvmByteArray([]byte(kvPath))
+	err := cSvmByteArray{}
+
+	defer func() {
+		cKVPath.Free()
+		err.SvmFree()
+	}()
+
+	if res := C.svm_runtime_create(
+		runtime,
+		cKVPath,
+		imports,
+		&err,
+	); res != cSvmSuccess {
+		return err.svmError()
+	}
+
+	return nil
+}
+
 func cSvmMemoryKVCreate(p *unsafe.Pointer) cSvmResultT {
 	return (cSvmResultT)(C.svm_memory_state_kv_create(p))
 }
@@ -204,6 +226,203 @@ func cSvmExecApp(runtime Runtime, tx []byte, appState []byte, gasMetering bool,
 	return svmByteArrayCloneToBytes(cReceipt), nil
 }
 
+func cSvmEstimateDeployTemplate(runtime Runtime, appTemplate []byte) (uint64, error) {
+	cEstimation := C.uint64_t(0)
+	cRuntime := runtime._inner
+	cAppTemplate := bytesCloneToSvmByteArray(appTemplate)
+	cErr := cSvmByteArray{}
+
+	defer func() {
+		cAppTemplate.Free()
+		cErr.SvmFree()
+	}()
+
+	if res := C.svm_estimate_deploy_template(
+		&cEstimation,
+		cRuntime,
+		cAppTemplate,
+		&cErr,
+	); res != cSvmSuccess {
+		return 0, cErr.svmError()
+	}
+
+	return uint64(cEstimation), nil
+}
+
+func cSvmEstimateSpawnApp(runtime Runtime, spawnApp []byte) (uint64, error) {
+	cEstimation := C.uint64_t(0)
+	cRuntime := runtime._inner
+	cSpawnApp := bytesCloneToSvmByteArray(spawnApp)
+	cErr := cSvmByteArray{}
+
+	defer func() {
+		cSpawnApp.Free()
+		cErr.SvmFree()
+	}()
+
+	if res := C.svm_estimate_spawn_app(
+		&cEstimation,
+		cRuntime,
+		cSpawnApp,
+		&cErr,
+	); res != cSvmSuccess {
+		return 0, cErr.svmError()
+	}
+
+	return uint64(cEstimation), nil
+}
+
+func cSvmEstimateExecApp(runtime Runtime, appTx []byte) (uint64, error) {
+	cEstimation := C.uint64_t(0)
+	cRuntime := runtime._inner
+	cAppTx := bytesCloneToSvmByteArray(appTx)
+	cErr := cSvmByteArray{}
+
+	defer func() {
+		cAppTx.Free()
+		cErr.SvmFree()
+	}()
+
+	if res := C.svm_estimate_exec_app(
+		&cEstimation,
+		cRuntime,
+		cAppTx,
+		&cErr,
+	); res != cSvmSuccess {
+		return 0, cErr.svmError()
+	}
+
+	return uint64(cEstimation), nil
+}
+
+func cSvmEncodeAppTemplate(version int, name string, code []byte, data []byte) ([]byte, error) {
+	cAppTemplate := cSvmByteArray{}
+	cVersion := C.uint32_t(version)
+	cName := bytesCloneToSvmByteArray([]byte(name))
+	cCode := bytesCloneToSvmByteArray(code)
+	cData := bytesCloneToSvmByteArray(data)
+	cErr := cSvmByteArray{}
+
+	defer func() {
+		cAppTemplate.SvmFree()
+		cName.Free()
+		cCode.Free()
+		cData.Free()
+		cErr.SvmFree()
+	}()
+
+	if res := C.svm_encode_app_template(
+		&cAppTemplate,
+		cVersion,
+		cName,
+		cCode,
+		cData,
+		&cErr,
+	); res != cSvmSuccess {
+		return nil, cErr.svmError()
+	}
+
+	return svmByteArrayCloneToBytes(cAppTemplate), nil
+}
+
+func cSvmEncodeSpawnApp(version int, templateAddr []byte, name string, ctorName string, calldata []byte) ([]byte, error) {
+	cSpawnApp := cSvmByteArray{}
+	cVersion := C.uint32_t(version)
+	cTemplateAddr := bytesCloneToSvmByteArray(templateAddr)
+	cName := bytesCloneToSvmByteArray([]byte(name))
+	cCtorName := bytesCloneToSvmByteArray([]byte(ctorName))
+	cCalldata := bytesCloneToSvmByteArray(calldata)
+	cErr := cSvmByteArray{}
+
+	defer func() {
+		cSpawnApp.SvmFree()
+		cTemplateAddr.Free()
+		cName.Free()
+		cCtorName.Free()
+		cCalldata.Free()
+		cErr.SvmFree()
+	}()
+
+	if res := C.svm_encode_spawn_app(
+		&cSpawnApp,
+		cVersion,
+		cTemplateAddr,
+		cName,
+		cCtorName,
+		cCalldata,
+		&cErr,
+	); res != cSvmSuccess {
+		return nil, cErr.svmError()
+	}
+
+	return svmByteArrayCloneToBytes(cSpawnApp), nil
+}
+
+func cSvmEncodeAppTx(version int, appAddr []byte, funcName string, calldata []byte) ([]byte, error) {
+	cAppTx := cSvmByteArray{}
+	cVersion := C.uint32_t(version)
+	cAppAddr := bytesCloneToSvmByteArray(appAddr)
+	cFuncName := bytesCloneToSvmByteArray([]byte(funcName))
+	cCalldata := bytesCloneToSvmByteArray(calldata)
+	cErr := cSvmByteArray{}
+
+	defer func() {
+		cAppTx.SvmFree()
+		cAppAddr.Free()
+		cFuncName.Free()
+		cCalldata.Free()
+		cErr.SvmFree()
+	}()
+
+	if res := C.svm_encode_app_tx(
+		&cAppTx,
+		cVersion,
+		cAppAddr,
+		cFuncName,
+		cCalldata,
+		&cErr,
+	); res != cSvmSuccess {
+		return nil, cErr.svmError()
+	}
+
+	return svmByteArrayCloneToBytes(cAppTx), nil
+}
+
+func cSvmTotalLiveResources() int {
+	return int(C.svm_total_live_resources())
+}
+
+func cSvmResources() []Resource {
+	iter := C.svm_resource_iter_new()
+	defer C.svm_resource_iter_destroy(iter)
+
+	resources := make([]Resource, 0)
+	for {
+		cResource := C.svm_resource_iter_next(iter)
+		if cResource == nil {
+			break
+		}
+
+		resources = append(resources, Resource{
+			TypeName: cSvmResourceTypeNameResolve(cResource.type_id),
+			Count:    int(cResource.count),
+		})
+		C.svm_resource_destroy(cResource)
+	}
+
+	return resources
+}
+
+func cSvmResourceTypeNameResolve(typeID C.uintptr_t) string {
+	cTypeName := C.svm_resource_type_name_resolve(typeID)
+	if cTypeName == nil {
+		return fmt.Sprintf("unknown (type id: %v)", typeID)
+	}
+	defer C.svm_resource_type_name_destroy(cTypeName)
+
+	return string(svmByteArrayCloneToBytes(*cTypeName))
+}
+
 func cSvmByteArrayDestroy(ba cSvmByteArray) {
 	C.svm_byte_array_destroy(ba)
 }
@@ -2,6 +2,7 @@ package svm
 
 import (
 	"encoding/binary"
+	"fmt"
 )
 
 type DataLayout []uint32
@@ -17,3 +18,17 @@ func (dl DataLayout) Encode() []byte {
 
 	return buf
 }
+
+// DecodeDataLayout decodes the variables' sizes encoded by `DataLayout.Encode`.
+func DecodeDataLayout(b []byte) (DataLayout, error) {
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("invalid data layout length; expected: a multiple of 4 bytes, given: %v", len(b))
+	}
+
+	dl := make(DataLayout, len(b)/4)
+	for i := range dl {
+		dl[i] = binary.BigEndian.Uint32(b[4*i:])
+	}
+
+	return dl, nil
+}
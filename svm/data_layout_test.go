@@ -16,3 +16,19 @@ func TestDataLayout_Encode(t *testing.T) {
 	req.Equal(uint32(20), binary.BigEndian.Uint32(b[4:]))
 	req.Equal(uint32(30), binary.BigEndian.Uint32(b[8:]))
 }
+
+func TestDecodeDataLayout(t *testing.T) {
+	req := require.New(t)
+
+	dl := DataLayout{10, 20, 30}
+	decoded, err := DecodeDataLayout(dl.Encode())
+	req.NoError(err)
+	req.Equal(dl, decoded)
+
+	decoded, err = DecodeDataLayout(nil)
+	req.NoError(err)
+	req.Empty(decoded)
+
+	_, err = DecodeDataLayout([]byte{0, 0, 0, 4, 0})
+	req.EqualError(err, "invalid data layout length; expected: a multiple of 4 bytes, given: 5")
+}
@@ -0,0 +1,58 @@
+package svm
+
+import "fmt"
+
+// EncodeTxDeployTemplate constructs a new raw `deploy template` transaction.
+// It produces the same output as `codec.EncodeTxDeployTemplate`, but uses the
+// SVM shared library directly instead of the `svm_codec.wasm` module.
+func EncodeTxDeployTemplate(version int, name string, code []byte, data []byte) ([]byte, error) {
+	if err := validateVersion(version); err != nil {
+		return nil, err
+	}
+
+	return cSvmEncodeAppTemplate(version, name, code, data)
+}
+
+// EncodeTxSpawnApp constructs a new raw `spawn app` transaction.
+// It produces the same output as `codec.EncodeTxSpawnApp`, but uses the
+// SVM shared library directly instead of the `svm_codec.wasm` module.
+func EncodeTxSpawnApp(version int, templateAddr []byte, name string, ctorName string, calldata []byte) ([]byte, error) {
+	if err := validateVersion(version); err != nil {
+		return nil, err
+	}
+	if err := validateAddress("template", templateAddr); err != nil {
+		return nil, err
+	}
+
+	return cSvmEncodeSpawnApp(version, templateAddr, name, ctorName, calldata)
+}
+
+// EncodeTxExecApp constructs a new raw `exec app` transaction.
+// It produces the same output as `codec.EncodeTxExecApp`, but uses the
+// SVM shared library directly instead of the `svm_codec.wasm` module.
+func EncodeTxExecApp(version int, appAddr []byte, funcName string, calldata []byte) ([]byte, error) {
+	if err := validateVersion(version); err != nil {
+		return nil, err
+	}
+	if err := validateAddress("app", appAddr); err != nil {
+		return nil, err
+	}
+
+	return cSvmEncodeAppTx(version, appAddr, funcName, calldata)
+}
+
+func validateVersion(version int) error {
+	if version < 0 || uint64(version) > uint64(^uint32(0)) {
+		return fmt.Errorf("invalid version: %v", version)
+	}
+
+	return nil
+}
+
+func validateAddress(kind string, addr []byte) error {
+	if len(addr) != AddressSize {
+		return fmt.Errorf("invalid %v address size; expected: %v, given: %v", kind, AddressSize, len(addr))
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package svm
+
+import (
+	"github.com/stretchr/testify/require"
+	"go-svm/codec"
+	"testing"
+)
+
+func TestEncodeTxDeployTemplate(t *testing.T) {
+	req := require.New(t)
+
+	code := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	data := DataLayout{4, 8}.Encode()
+
+	expected, err := codec.EncodeTxDeployTemplate(0, "name", code, data)
+	req.NoError(err)
+
+	actual, err := EncodeTxDeployTemplate(0, "name", code, data)
+	req.NoError(err)
+	req.Equal(expected, actual)
+}
+
+func TestEncodeTxSpawnApp(t *testing.T) {
+	req := require.New(t)
+
+	templateAddr := BytesToAddress([]byte{0x10, 0x20, 0x30})
+	calldata, err := codec.EncodeCallData([]string{"u32"}, []int{10})
+	req.NoError(err)
+
+	expected, err := codec.EncodeTxSpawnApp(0, templateAddr[:], "name", "initialize", calldata)
+	req.NoError(err)
+
+	actual, err := EncodeTxSpawnApp(0, templateAddr[:], "name", "initialize", calldata)
+	req.NoError(err)
+	req.Equal(expected, actual)
+}
+
+func TestEncodeTxExecApp(t *testing.T) {
+	req := require.New(t)
+
+	appAddr := BytesToAddress([]byte{0x40, 0x50, 0x60})
+	calldata, err := codec.EncodeCallData([]string{"u32"}, []int{5})
+	req.NoError(err)
+
+	expected, err := codec.EncodeTxExecApp(0, appAddr[:], "counter_add", calldata)
+	req.NoError(err)
+
+	actual, err := EncodeTxExecApp(0, appAddr[:], "counter_add", calldata)
+	req.NoError(err)
+	req.Equal(expected, actual)
+}
+
+func TestEncodeTx_InvalidInput(t *testing.T) {
+	req := require.New(t)
+
+	_, err := EncodeTxDeployTemplate(-1, "name", nil, nil)
+	req.EqualError(err, "invalid version: -1")
+
+	_, err = EncodeTxSpawnApp(0, make([]byte, AddressSize-1), "name", "initialize", nil)
+	req.EqualError(err, "invalid template address size; expected: 20, given: 19")
+
+	_, err = EncodeTxExecApp(0, make([]byte, AddressSize+1), "counter_add", nil)
+	req.EqualError(err, "invalid app address size; expected: 20, given: 21")
+}
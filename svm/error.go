@@ -2,17 +2,33 @@ package svm
 
 import "fmt"
 
-// svmError is error type which represent an error originated in the SVM runtime.
-type svmError struct {
-	s string
+// RuntimeError is error type which represent an error originated in the SVM runtime.
+type RuntimeError struct {
+	Msg string
 }
 
-// newSvmError creates a new svmError instance from []byte slice.
+// newSvmError creates a new RuntimeError instance from []byte slice.
 func newSvmError(b []byte) error {
-	return &svmError{s: string(b)}
+	return &RuntimeError{Msg: string(b)}
 }
 
-// Error helps svmError to implement the error interface.
-func (e *svmError) Error() string {
-	return fmt.Sprintf("svm error: %v", e.s)
+// Error helps RuntimeError to implement the error interface.
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("svm error: %v", e.Msg)
+}
+
+// InvalidTxError is returned when a raw transaction is rejected
+// before being handed over to the SVM runtime.
+type InvalidTxError struct {
+	Err error
+}
+
+// Error helps InvalidTxError to implement the error interface.
+func (e *InvalidTxError) Error() string {
+	return fmt.Sprintf("invalid transaction: %v", e.Err)
+}
+
+// Unwrap returns the underlying validation error.
+func (e *InvalidTxError) Unwrap() error {
+	return e.Err
 }
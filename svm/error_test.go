@@ -1,6 +1,8 @@
 package svm
 
 import (
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -12,4 +14,9 @@ func TestSvmError(t *testing.T) {
 	goError := err.svmError()
 
 	req.Equal("svm error: Mayday", goError.Error())
+
+	wrapped := fmt.Errorf("failed to create runtime: %w", goError)
+	var runtimeErr *RuntimeError
+	req.True(errors.As(wrapped, &runtimeErr))
+	req.Equal("Mayday", runtimeErr.Msg)
 }
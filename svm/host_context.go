@@ -0,0 +1,154 @@
+package svm
+
+// #include <stdint.h>
+//
+// #ifdef _WIN32
+// #include <windows.h>
+// static uintptr_t svm_current_thread(void) { return (uintptr_t)GetCurrentThreadId(); }
+// #else
+// #include <pthread.h>
+// static uintptr_t svm_current_thread(void) { return (uintptr_t)pthread_self(); }
+// #endif
+//
+import "C"
+import (
+	"runtime"
+	"sync"
+)
+
+// HostContext holds information about the transaction execution
+// which invoked a context-aware host import function.
+//
+// SVM doesn't expose its inner execution environment, so the context is
+// established by the Go side around each execution (see `ExecAppWithContext`
+// and `SpawnAppWithContext`), and looked up by the OS thread which SVM invokes
+// the host function on. Host functions invoked from any other execution get
+// a zero-value context.
+type HostContext struct {
+	// AppAddr is the address of the app which invoked the host function.
+	// It is taken from the executed transaction. It is zero while spawning an app,
+	// since the app's address is only known once it's spawned.
+	AppAddr Address
+
+	// TemplateAddr is the address of the template of the invoking app.
+	// It is taken from the spawn transaction, or from the runtime's record
+	// of the apps it spawned when executing an app. When executing any other app,
+	// it's supplied by the caller (see `ExecAppWithContext`).
+	TemplateAddr Address
+
+	// Sender is the address of the transaction sender. When spawning an app, it's the app's creator.
+	// SVM's exec-app transactions don't carry a sender, so when executing an app,
+	// it's supplied by the caller, which is responsible for authenticating it.
+	Sender Address
+
+	// GasMetering and GasLimit are the gas settings of the execution.
+	// SVM doesn't report the gas consumed during an execution until it completes,
+	// so the remaining gas isn't available to host functions.
+	GasMetering bool
+	GasLimit    uint64
+
+	// Value is a user-supplied, per-execution value.
+	Value interface{}
+}
+
+type hostFunctionCtx func(*HostContext, []Value) ([]Value, error)
+
+// hostContextStore is a static container for the contexts of the executions currently running
+// on each OS thread, to be accessed from the unsafe, cgo-exported `svm_trampoline`.
+//
+// SVM invokes `svm_trampoline` on the thread which called into it, so the thread identifies
+// the execution which invoked a host function, even when runtimes sharing an Imports instance
+// are used concurrently. Each thread holds a stack of contexts, since a host function may
+// start a nested execution.
+var hostContextStore = hostContexts{
+	stacks: make(map[uintptr][]*HostContext),
+}
+
+type hostContexts struct {
+	sync.RWMutex
+	stacks map[uintptr][]*HostContext
+}
+
+// currentThread returns an identifier of the current OS thread.
+func currentThread() uintptr {
+	return uintptr(C.svm_current_thread())
+}
+
+func (hc *hostContexts) push(thread uintptr, ctx *HostContext) {
+	hc.Lock()
+	defer hc.Unlock()
+
+	hc.stacks[thread] = append(hc.stacks[thread], ctx)
+}
+
+func (hc *hostContexts) pop(thread uintptr) {
+	hc.Lock()
+	defer hc.Unlock()
+
+	stack := hc.stacks[thread]
+	if len(stack) <= 1 {
+		delete(hc.stacks, thread)
+		return
+	}
+
+	stack[len(stack)-1] = nil
+	hc.stacks[thread] = stack[:len(stack)-1]
+}
+
+// current returns the context of the innermost execution running on the given thread,
+// or a zero-value context if there isn't any.
+func (hc *hostContexts) current(thread uintptr) *HostContext {
+	hc.RLock()
+	defer hc.RUnlock()
+
+	stack := hc.stacks[thread]
+	if len(stack) == 0 {
+		return &HostContext{}
+	}
+
+	return stack[len(stack)-1]
+}
+
+// withHostContext runs an execution of the runtime, while exposing `ctx` to its host functions.
+func (r Runtime) withHostContext(ctx *HostContext, exec func()) {
+	// Keep the goroutine on the thread which `exec` calls into SVM from.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	thread := currentThread()
+	hostContextStore.push(thread, ctx)
+	defer hostContextStore.pop(thread)
+
+	exec()
+}
+
+// appTemplates records the template of each app spawned by a runtime,
+// so that executions of an app expose its actual template to host functions.
+type appTemplates struct {
+	sync.RWMutex
+	templates map[Address]Address
+}
+
+func newAppTemplates() *appTemplates {
+	return &appTemplates{templates: make(map[Address]Address)}
+}
+
+func (at *appTemplates) add(appAddr, templateAddr Address) {
+	at.Lock()
+	defer at.Unlock()
+
+	at.templates[appAddr] = templateAddr
+}
+
+// template returns the template of the app at `appAddr`, if the app was spawned by the runtime.
+func (at *appTemplates) template(appAddr Address) (Address, bool) {
+	if at == nil {
+		return Address{}, false
+	}
+
+	at.RLock()
+	defer at.RUnlock()
+
+	templateAddr, ok := at.templates[appAddr]
+	return templateAddr, ok
+}
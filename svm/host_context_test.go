@@ -0,0 +1,204 @@
+package svm
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"go-svm/codec"
+	"io/ioutil"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestHostContexts(t *testing.T) {
+	req := require.New(t)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	thread := currentThread()
+
+	req.Equal(&HostContext{}, hostContextStore.current(thread))
+
+	outer := &HostContext{Sender: BytesToAddress([]byte{1}), Value: "outer"}
+	inner := &HostContext{Sender: BytesToAddress([]byte{2}), Value: "inner"}
+	Runtime{}.withHostContext(outer, func() {
+		req.Equal(outer, hostContextStore.current(thread))
+
+		// A host function may start a nested execution.
+		Runtime{}.withHostContext(inner, func() {
+			req.Equal(inner, hostContextStore.current(thread))
+		})
+		req.Equal(outer, hostContextStore.current(thread))
+	})
+	req.Equal(&HostContext{}, hostContextStore.current(thread))
+}
+
+func TestHostContexts_Concurrent(t *testing.T) {
+	const n = 4
+
+	// All the executions run at the same time, each observing its own context.
+	var started, done sync.WaitGroup
+	started.Add(n)
+	done.Add(n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer done.Done()
+
+			ctx := &HostContext{Value: i}
+			Runtime{}.withHostContext(ctx, func() {
+				started.Done()
+				started.Wait()
+
+				if got := hostContextStore.current(currentThread()); got != ctx {
+					errs <- fmt.Errorf("execution #%v: unexpected context: %+v", i, got)
+				}
+			})
+		}(i)
+	}
+	done.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestInvokeHostFunction_Context(t *testing.T) {
+	req := require.New(t)
+
+	var got *HostContext
+	index := hostFunctionStore.add(func(ctx *HostContext, args []Value) ([]Value, error) {
+		got = ctx
+		return nil, nil
+	})
+
+	ctx := &HostContext{Sender: BytesToAddress([]byte{1}), Value: 42}
+	_, err := invokeHostFunction(index, ctx, Values{}.Encode())
+	req.NoError(err)
+	req.Equal(ctx, got)
+}
+
+func TestExecAppWithContext(t *testing.T) {
+	req := require.New(t)
+
+	code, err := ioutil.ReadFile(counterTemplateFilename)
+	req.NoError(err)
+
+	var contexts []HostContext
+	imports, err := NewImportsBuilder().
+		RegisterFunctionCtx("add", ValueTypes{TypeI32, TypeI32}, ValueTypes{TypeI32},
+			func(ctx *HostContext, args []Value) ([]Value, error) {
+				contexts = append(contexts, *ctx)
+				return []Value{I32(args[0].ToI32() + args[1].ToI32())}, nil
+			}).
+		RegisterFunction("mul", ValueTypes{TypeI32, TypeI32}, ValueTypes{TypeI32},
+			func(args []Value) ([]Value, error) {
+				return []Value{I32(args[0].ToI32() * args[1].ToI32())}, nil
+			}).
+		Build()
+	req.NoError(err)
+	defer imports.Free()
+
+	kv, err := NewStateKV_Mem()
+	req.NoError(err)
+	defer kv.Free()
+
+	runtime, err := NewRuntimeBuilder().
+		WithImports(imports).
+		WithStateKV_Mem(&kv).
+		Build()
+	req.NoError(err)
+	defer runtime.Free()
+
+	tx, err := codec.EncodeTxDeployTemplate(0, "counter", code, DataLayout{4}.Encode())
+	req.NoError(err)
+	deployReceipt, err := DeployTemplate(runtime, tx, Address{}, false, 0)
+	req.NoError(err)
+
+	calldata, err := codec.EncodeCallData([]string{"u32"}, []int{10})
+	req.NoError(err)
+	tx, err = codec.EncodeTxSpawnApp(0, deployReceipt.TemplateAddr[:], "counter", "initialize", calldata)
+	req.NoError(err)
+	spawnReceipt, err := SpawnAppWithContext(runtime, tx, Address{}, false, 0, HostContext{})
+	req.NoError(err)
+
+	calldata, err = codec.EncodeCallData([]string{"u32"}, []int{5})
+	req.NoError(err)
+	tx, err = codec.EncodeTxExecApp(0, spawnReceipt.AppAddr[:], "counter_add", calldata)
+	req.NoError(err)
+
+	// The app and template addresses can't be forged by the caller.
+	sender := BytesToAddress([]byte{0xaa})
+	forged := BytesToAddress([]byte{0xbb})
+	_, err = ExecAppWithContext(runtime, tx, spawnReceipt.State, false, 0, HostContext{
+		AppAddr:      forged,
+		TemplateAddr: forged,
+		Sender:       sender,
+		Value:        "tx #1",
+	})
+	req.NoError(err)
+
+	// Executions without a context expose a zero-value context.
+	_, err = ExecApp(runtime, tx, spawnReceipt.State, false, 0)
+	req.NoError(err)
+
+	req.Equal([]HostContext{
+		{
+			AppAddr:      spawnReceipt.AppAddr,
+			TemplateAddr: deployReceipt.TemplateAddr,
+			Sender:       sender,
+			Value:        "tx #1",
+		},
+		{},
+	}, contexts)
+
+	// A runtime which didn't spawn the app, e.g., after a restart, keeps the given template.
+	other, err := NewRuntimeBuilder().
+		WithImports(imports).
+		WithStateKV_Mem(&kv).
+		Build()
+	req.NoError(err)
+	defer other.Free()
+
+	contexts = nil
+	_, err = ExecAppWithContext(other, tx, spawnReceipt.State, false, 0, HostContext{
+		AppAddr:      forged,
+		TemplateAddr: deployReceipt.TemplateAddr,
+		Sender:       sender,
+	})
+	req.NoError(err)
+	req.Equal([]HostContext{
+		{
+			AppAddr:      spawnReceipt.AppAddr,
+			TemplateAddr: deployReceipt.TemplateAddr,
+			Sender:       sender,
+		},
+	}, contexts)
+}
+
+func TestAppTemplates(t *testing.T) {
+	req := require.New(t)
+
+	appAddr := BytesToAddress([]byte{1})
+	templateAddr := BytesToAddress([]byte{2})
+
+	apps := newAppTemplates()
+	_, ok := apps.template(appAddr)
+	req.False(ok)
+
+	apps.add(appAddr, templateAddr)
+	addr, ok := apps.template(appAddr)
+	req.True(ok)
+	req.Equal(templateAddr, addr)
+
+	// Runtimes which weren't built by a RuntimeBuilder don't know any app.
+	_, ok = Runtime{}.apps.template(appAddr)
+	req.False(ok)
+}
+
+func TestSpawnAppWithContext_InvalidTx(t *testing.T) {
+	_, err := SpawnAppWithContext(Runtime{}, []byte{0x01}, Address{}, false, 0, HostContext{})
+	require.Error(t, err)
+	require.IsType(t, &InvalidTxError{}, err)
+}
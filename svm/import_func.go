@@ -57,21 +57,11 @@ import (
 
 //export svm_trampoline
 func svm_trampoline(env *C.svm_env_t, args *C.svm_byte_array, results *C.svm_byte_array) *C.svm_byte_array {
-	// Fetch the target function.
 	hostEnv := (*functionEnvironment)(env.host_env)
-	f := hostFunctionStore.get(hostEnv.hostFunctionStoreIndex)
-	if f == nil {
-		panic(fmt.Sprintf("go-svm: host import function not found; index: %v", hostEnv.hostFunctionStoreIndex))
-	}
 
-	// Decode args.
-	goArgs := Values{}
-	if err := goArgs.Decode(svmByteArrayCloneToBytes(*args)); err != nil {
-		panic(fmt.Sprintf("go-svm: %v", err))
-	}
+	ctx := hostContextStore.current(currentThread())
 
-	// Invoke.
-	goResults, err := f(goArgs)
+	rawResults, err := invokeHostFunction(hostEnv.hostFunctionStoreIndex, ctx, svmByteArrayCloneToBytes(*args))
 	if err != nil {
 		err := []byte(err.Error())
 		cErr := bytesAliasToSvmByteArray(err)
@@ -84,13 +74,47 @@ func svm_trampoline(env *C.svm_env_t, args *C.svm_byte_array, results *C.svm_byt
 		return cSvmErr
 	}
 
-	// Encode results.
-	rawResults := Values(goResults).Encode()
 	*results = bytesCloneToSvmByteArray(rawResults)
 
 	return nil
 }
 
+// invokeHostFunction decodes the args, invokes the host function registered
+// under the given index, and encodes its results.
+//
+// Since a panic can't unwind through the SVM (Rust) frames, any panic raised
+// by the host function is recovered and returned as an error instead,
+// so that the transaction fails rather than the process.
+func invokeHostFunction(index uint, ctx *HostContext, rawArgs []byte) (rawResults []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			rawResults = nil
+			err = fmt.Errorf("go-svm: host import function panicked; index: %v, panic: %v", index, r)
+		}
+	}()
+
+	// Fetch the target function.
+	f := hostFunctionStore.get(index)
+	if f == nil {
+		return nil, fmt.Errorf("go-svm: host import function not found; index: %v", index)
+	}
+
+	// Decode args.
+	goArgs := Values{}
+	if err := goArgs.Decode(rawArgs); err != nil {
+		return nil, fmt.Errorf("go-svm: %v", err)
+	}
+
+	// Invoke.
+	goResults, err := f(ctx, goArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encode results.
+	return Values(goResults).Encode(), nil
+}
+
 func cSvmWasmErrorCreate(err cSvmByteArray) *cSvmByteArray {
 	return (*cSvmByteArray)(C.svm_wasm_error_create(err))
 }
@@ -104,19 +128,22 @@ type hostFunction func([]Value) ([]Value, error)
 // hostFunctionStore is a static container for the registered import functions, written in Go,
 // to be invoked from the unsafe, cgo-exported `svm_trampoline`.
 var hostFunctionStore = hostFunctions{
-	functions: make(map[uint]hostFunction),
+	functions: make(map[uint]hostFunctionCtx),
 }
 
 type hostFunctions struct {
 	sync.RWMutex
-	functions map[uint]hostFunction
+	functions map[uint]hostFunctionCtx
 }
 
-func (hf *hostFunctions) get(index uint) hostFunction {
+func (hf *hostFunctions) get(index uint) hostFunctionCtx {
+	hf.RLock()
+	defer hf.RUnlock()
+
 	return hf.functions[index]
 }
 
-func (hf *hostFunctions) add(function hostFunction) uint {
+func (hf *hostFunctions) add(function hostFunctionCtx) uint {
 	hf.Lock()
 	defer hf.Unlock()
 
@@ -0,0 +1,160 @@
+package svm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	hostContextType = reflect.TypeOf((*HostContext)(nil))
+	errorType       = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// goFuncArg converts an SVM value into a Go value of a particular type.
+type goFuncArg func(Value) reflect.Value
+
+// goFuncResult converts a Go value of a particular type into an SVM value.
+type goFuncResult func(reflect.Value) Value
+
+// wrapGoFunc derives the WebAssembly signature of an ordinary Go function,
+// and wraps it as a host import function.
+//
+// The function params and returns types must be one of: int32, uint32, int64, uint64
+// (or types defined over them). Additionally, the function may accept a *HostContext
+// as its first param, and may return an error as its last return value.
+//
+// The marshalling of the args and results is prepared once, here,
+// so that invocations don't need to inspect the function type.
+func wrapGoFunc(fn interface{}) (hostFunctionCtx, ValueTypes, ValueTypes, error) {
+	if fn == nil {
+		return nil, nil, nil, errors.New("function is nil")
+	}
+
+	fnValue := reflect.ValueOf(fn)
+	fnType := fnValue.Type()
+	if fnType.Kind() != reflect.Func {
+		return nil, nil, nil, fmt.Errorf("expected a function, given: %v", fnType)
+	}
+	if fnValue.IsNil() {
+		return nil, nil, nil, errors.New("function is nil")
+	}
+	if fnType.IsVariadic() {
+		return nil, nil, nil, fmt.Errorf("variadic functions aren't supported: %v", fnType)
+	}
+
+	// Params.
+	firstArg := 0
+	withContext := fnType.NumIn() > 0 && fnType.In(0) == hostContextType
+	if withContext {
+		firstArg = 1
+	}
+
+	params := make(ValueTypes, 0, fnType.NumIn()-firstArg)
+	args := make([]goFuncArg, 0, fnType.NumIn()-firstArg)
+	for i := firstArg; i < fnType.NumIn(); i++ {
+		t := fnType.In(i)
+		vt, arg, err := goFuncArgOf(t)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("unsupported param #%v type: %v", i, t)
+		}
+		params = append(params, vt)
+		args = append(args, arg)
+	}
+
+	// Returns.
+	numOut := fnType.NumOut()
+	withError := numOut > 0 && fnType.Out(numOut-1) == errorType
+	if withError {
+		numOut--
+	}
+
+	returns := make(ValueTypes, 0, numOut)
+	results := make([]goFuncResult, 0, numOut)
+	for i := 0; i < numOut; i++ {
+		t := fnType.Out(i)
+		vt, result, err := goFuncResultOf(t)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("unsupported return #%v type: %v", i, t)
+		}
+		returns = append(returns, vt)
+		results = append(results, result)
+	}
+
+	f := func(ctx *HostContext, svmArgs []Value) ([]Value, error) {
+		if len(svmArgs) != len(args) {
+			return nil, fmt.Errorf("invalid number of args; expected: %v, given: %v", len(args), len(svmArgs))
+		}
+
+		in := make([]reflect.Value, 0, firstArg+len(args))
+		if withContext {
+			in = append(in, reflect.ValueOf(ctx))
+		}
+		for i, arg := range args {
+			in = append(in, arg(svmArgs[i]))
+		}
+
+		out := fnValue.Call(in)
+
+		if withError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+		}
+
+		svmResults := make([]Value, len(results))
+		for i, result := range results {
+			svmResults[i] = result(out[i])
+		}
+
+		return svmResults, nil
+	}
+
+	return f, params, returns, nil
+}
+
+func goFuncArgOf(t reflect.Type) (ValueType, goFuncArg, error) {
+	switch t.Kind() {
+	case reflect.Int32:
+		return TypeI32, func(v Value) reflect.Value {
+			return reflect.ValueOf(v.ToI32()).Convert(t)
+		}, nil
+	case reflect.Uint32:
+		return TypeI32, func(v Value) reflect.Value {
+			return reflect.ValueOf(uint32(v.ToI32())).Convert(t)
+		}, nil
+	case reflect.Int64:
+		return TypeI64, func(v Value) reflect.Value {
+			return reflect.ValueOf(v.ToI64()).Convert(t)
+		}, nil
+	case reflect.Uint64:
+		return TypeI64, func(v Value) reflect.Value {
+			return reflect.ValueOf(uint64(v.ToI64())).Convert(t)
+		}, nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported type: %v", t)
+	}
+}
+
+func goFuncResultOf(t reflect.Type) (ValueType, goFuncResult, error) {
+	switch t.Kind() {
+	case reflect.Int32:
+		return TypeI32, func(v reflect.Value) Value {
+			return I32(int32(v.Int()))
+		}, nil
+	case reflect.Uint32:
+		return TypeI32, func(v reflect.Value) Value {
+			return I32(int32(uint32(v.Uint())))
+		}, nil
+	case reflect.Int64:
+		return TypeI64, func(v reflect.Value) Value {
+			return I64(v.Int())
+		}, nil
+	case reflect.Uint64:
+		return TypeI64, func(v reflect.Value) Value {
+			return I64(int64(v.Uint()))
+		}, nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported type: %v", t)
+	}
+}
@@ -0,0 +1,100 @@
+package svm
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWrapGoFunc(t *testing.T) {
+	req := require.New(t)
+
+	f, params, returns, err := wrapGoFunc(func(a int32, b uint32, c int64, d uint64) (int32, uint32, int64, uint64) {
+		return a + 1, b + 1, c + 1, d + 1
+	})
+	req.NoError(err)
+	req.Equal(ValueTypes{TypeI32, TypeI32, TypeI64, TypeI64}, params)
+	req.Equal(ValueTypes{TypeI32, TypeI32, TypeI64, TypeI64}, returns)
+
+	results, err := f(&HostContext{}, []Value{I32(-2), I32(-1), I64(-2), I64(-1)})
+	req.NoError(err)
+	req.Equal([]Value{I32(-1), I32(0), I64(-1), I64(0)}, results)
+
+	_, err = f(&HostContext{}, []Value{I32(1)})
+	req.EqualError(err, "invalid number of args; expected: 4, given: 1")
+}
+
+func TestWrapGoFunc_ContextAndError(t *testing.T) {
+	req := require.New(t)
+
+	type amount uint64
+
+	f, params, returns, err := wrapGoFunc(func(ctx *HostContext, a amount) (amount, error) {
+		if a == 0 {
+			return 0, errors.New("zero amount")
+		}
+		return a * amount(ctx.Value.(int)), nil
+	})
+	req.NoError(err)
+	req.Equal(ValueTypes{TypeI64}, params)
+	req.Equal(ValueTypes{TypeI64}, returns)
+
+	ctx := &HostContext{Value: 3}
+	results, err := f(ctx, []Value{I64(5)})
+	req.NoError(err)
+	req.Equal([]Value{I64(15)}, results)
+
+	_, err = f(ctx, []Value{I64(0)})
+	req.EqualError(err, "zero amount")
+}
+
+func TestWrapGoFunc_NoParamsNoReturns(t *testing.T) {
+	req := require.New(t)
+
+	called := false
+	f, params, returns, err := wrapGoFunc(func() { called = true })
+	req.NoError(err)
+	req.Empty(params)
+	req.Empty(returns)
+
+	results, err := f(&HostContext{}, nil)
+	req.NoError(err)
+	req.Empty(results)
+	req.True(called)
+}
+
+func TestWrapGoFunc_Unsupported(t *testing.T) {
+	req := require.New(t)
+
+	var nilFunc func(int32)
+
+	cases := []struct {
+		fn  interface{}
+		err string
+	}{
+		{nil, "function is nil"},
+		{nilFunc, "function is nil"},
+		{42, "expected a function, given: int"},
+		{func(a ...int32) {}, "variadic functions aren't supported: func(...int32)"},
+		{func(a int) {}, "unsupported param #0 type: int"},
+		{func(a int32, s string) {}, "unsupported param #1 type: string"},
+		{func(a int32, ctx *HostContext) {}, "unsupported param #1 type: *svm.HostContext"},
+		{func() float32 { return 0 }, "unsupported return #0 type: float32"},
+		{func() (error, int32) { return nil, 0 }, "unsupported return #0 type: error"},
+	}
+
+	for _, c := range cases {
+		_, _, _, err := wrapGoFunc(c.fn)
+		req.EqualError(err, c.err)
+	}
+}
+
+func TestImportsBuilder_RegisterGoFunc_Unsupported(t *testing.T) {
+	req := require.New(t)
+
+	_, err := NewImportsBuilder().
+		RegisterGoFunc("add", func(a, b int32) (int32, error) { return a + b, nil }).
+		RegisterGoFunc("concat", func(a, b string) string { return a + b }).
+		Build()
+	req.EqualError(err, "failed to build import function `concat`: unsupported param #0 type: string")
+}
@@ -0,0 +1,107 @@
+package svm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"go-svm/codec"
+	"io/ioutil"
+	"testing"
+)
+
+func TestInvokeHostFunction(t *testing.T) {
+	req := require.New(t)
+
+	index := hostFunctionStore.add(func(_ *HostContext, args []Value) ([]Value, error) {
+		return []Value{I32(args[0].ToI32() + args[1].ToI32())}, nil
+	})
+
+	rawResults, err := invokeHostFunction(index, &HostContext{}, Values{I32(1), I32(2)}.Encode())
+	req.NoError(err)
+	req.Equal(Values{I32(3)}.Encode(), rawResults)
+}
+
+func TestInvokeHostFunction_Errors(t *testing.T) {
+	req := require.New(t)
+
+	index := hostFunctionStore.add(func(_ *HostContext, args []Value) ([]Value, error) {
+		return nil, errors.New("Mayday")
+	})
+	_, err := invokeHostFunction(index, &HostContext{}, Values{}.Encode())
+	req.EqualError(err, "Mayday")
+
+	_, err = invokeHostFunction(index, &HostContext{}, nil)
+	req.EqualError(err, "go-svm: invalid input: empty data")
+
+	_, err = invokeHostFunction(^uint(0), &HostContext{}, Values{}.Encode())
+	req.EqualError(err, "go-svm: host import function not found; index: 18446744073709551615")
+}
+
+func TestInvokeHostFunction_Panic(t *testing.T) {
+	req := require.New(t)
+
+	index := hostFunctionStore.add(func(_ *HostContext, args []Value) ([]Value, error) {
+		panic("Mayday")
+	})
+	_, err := invokeHostFunction(index, &HostContext{}, Values{}.Encode())
+	req.EqualError(err, fmt.Sprintf("go-svm: host import function panicked; index: %v, panic: Mayday", index))
+
+	index = hostFunctionStore.add(func(_ *HostContext, args []Value) ([]Value, error) {
+		return []Value{args[5]}, nil
+	})
+	_, err = invokeHostFunction(index, &HostContext{}, Values{}.Encode())
+	req.Error(err)
+	req.Contains(err.Error(), "go-svm: host import function panicked")
+	req.Contains(err.Error(), "index out of range")
+}
+
+func TestHostFunctionPanic_FailsTx(t *testing.T) {
+	req := require.New(t)
+
+	code, err := ioutil.ReadFile(counterTemplateFilename)
+	req.NoError(err)
+
+	imports, err := NewImportsBuilder().
+		RegisterFunction("add", ValueTypes{TypeI32, TypeI32}, ValueTypes{TypeI32},
+			func(args []Value) ([]Value, error) {
+				panic("Mayday")
+			}).
+		RegisterFunction("mul", ValueTypes{TypeI32, TypeI32}, ValueTypes{TypeI32},
+			func(args []Value) ([]Value, error) {
+				return []Value{I32(args[0].ToI32() * args[1].ToI32())}, nil
+			}).
+		Build()
+	req.NoError(err)
+	defer imports.Free()
+
+	kv, err := NewStateKV_Mem()
+	req.NoError(err)
+	defer kv.Free()
+
+	runtime, err := NewRuntimeBuilder().
+		WithImports(imports).
+		WithStateKV_Mem(&kv).
+		Build()
+	req.NoError(err)
+	defer runtime.Free()
+
+	tx, err := codec.EncodeTxDeployTemplate(0, "counter", code, DataLayout{4}.Encode())
+	req.NoError(err)
+	deployReceipt, err := DeployTemplate(runtime, tx, Address{}, false, 0)
+	req.NoError(err)
+
+	calldata, err := codec.EncodeCallData([]string{"u32"}, []int{10})
+	req.NoError(err)
+	tx, err = codec.EncodeTxSpawnApp(0, deployReceipt.TemplateAddr[:], "counter", "initialize", calldata)
+	req.NoError(err)
+	spawnReceipt, err := SpawnApp(runtime, tx, Address{}, false, 0)
+	req.NoError(err)
+
+	calldata, err = codec.EncodeCallData([]string{"u32"}, []int{5})
+	req.NoError(err)
+	tx, err = codec.EncodeTxExecApp(0, spawnReceipt.AppAddr[:], "counter_add", calldata)
+	req.NoError(err)
+	_, err = ExecApp(runtime, tx, spawnReceipt.State, false, 0)
+	req.Error(err)
+	req.Contains(err.Error(), "host import function panicked")
+}
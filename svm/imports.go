@@ -7,6 +7,7 @@ package svm
 import "C"
 import (
 	"fmt"
+	"sort"
 	"unsafe"
 )
 
@@ -29,7 +30,7 @@ func (imports Imports) Free() {
 // ImportFunction represents an SVM-runtime imported function.
 type ImportFunction struct {
 	// f represents the actual function implementation, written in Go.
-	f hostFunction
+	f hostFunctionCtx
 
 	// params is the WebAssembly signature of the function implementation params.
 	params ValueTypes
@@ -39,6 +40,10 @@ type ImportFunction struct {
 
 	// namespace is the imported function WebAssembly namespace.
 	namespace string
+
+	// err is the reason the function can't be imported, if any.
+	// It is reported by `ImportsBuilder.Build`.
+	err error
 }
 
 type ImportsBuilder struct {
@@ -60,17 +65,59 @@ func (ib ImportsBuilder) Namespace(namespace string) ImportsBuilder {
 }
 
 func (ib ImportsBuilder) RegisterFunction(name string, params ValueTypes, returns ValueTypes, f hostFunction) ImportsBuilder {
+	return ib.RegisterFunctionCtx(name, params, returns, func(_ *HostContext, args []Value) ([]Value, error) {
+		return f(args)
+	})
+}
+
+// RegisterFunctionCtx registers a context-aware host import function,
+// which gets the HostContext of the execution which invoked it.
+func (ib ImportsBuilder) RegisterFunctionCtx(name string, params ValueTypes, returns ValueTypes,
+	f func(ctx *HostContext, args []Value) ([]Value, error)) ImportsBuilder {
 	ib.imports[name] = ImportFunction{
 		f,
 		params,
 		returns,
 		ib.currentNamespace,
+		nil,
+	}
+
+	return ib
+}
+
+// RegisterGoFunc registers an ordinary Go function as a host import function,
+// deriving its WebAssembly signature from the function type, e.g.:
+//
+//     func(a, b int32) (int32, error)
+//
+// Params and returns must be of type int32, uint32, int64 or uint64. The function
+// may also accept a *HostContext as its first param, and return an error as its last
+// return value. Unsupported functions are reported by `Build`.
+func (ib ImportsBuilder) RegisterGoFunc(name string, fn interface{}) ImportsBuilder {
+	f, params, returns, err := wrapGoFunc(fn)
+	ib.imports[name] = ImportFunction{
+		f,
+		params,
+		returns,
+		ib.currentNamespace,
+		err,
 	}
 
 	return ib
 }
 
 func (ib ImportsBuilder) Build() (*Imports, error) {
+	names := make([]string, 0, len(ib.imports))
+	for name := range ib.imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := ib.imports[name].err; err != nil {
+			return nil, fmt.Errorf("failed to build import function `%v`: %v", name, err)
+		}
+	}
+
 	imports := Imports{}
 	imports.envs = make([]*functionEnvironment, 0)
 
@@ -94,7 +141,7 @@ func (ib ImportsBuilder) Build() (*Imports, error) {
 			imprt.params,
 			imprt.returns,
 		); err != nil {
-			return nil, fmt.Errorf("failed to build import function `%v`: %v", imprtName, err)
+			return nil, fmt.Errorf("failed to build import function `%v`: %w", imprtName, err)
 		}
 	}
 
@@ -0,0 +1,264 @@
+package svm
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VarType is the type of an app storage variable, which determines its size in bytes.
+type VarType struct {
+	name string
+	size uint32
+}
+
+var (
+	VarBool    = VarType{"bool", 1}
+	VarI8      = VarType{"i8", 1}
+	VarU8      = VarType{"u8", 1}
+	VarI16     = VarType{"i16", 2}
+	VarU16     = VarType{"u16", 2}
+	VarI32     = VarType{"i32", 4}
+	VarU32     = VarType{"u32", 4}
+	VarI64     = VarType{"i64", 8}
+	VarU64     = VarType{"u64", 8}
+	VarAmount  = VarType{"amount", 8}
+	VarAddress = VarType{"address", AddressSize}
+)
+
+// varTypes holds the fixed-size variable types, by name.
+var varTypes = map[string]VarType{
+	VarBool.name:    VarBool,
+	VarI8.name:      VarI8,
+	VarU8.name:      VarU8,
+	VarI16.name:     VarI16,
+	VarU16.name:     VarU16,
+	VarI32.name:     VarI32,
+	VarU32.name:     VarU32,
+	VarI64.name:     VarI64,
+	VarU64.name:     VarU64,
+	VarAmount.name:  VarAmount,
+	VarAddress.name: VarAddress,
+}
+
+// VarBytes returns the type of a variable holding `size` raw bytes, named `bytes<size>`, e.g., `bytes32`.
+func VarBytes(size uint32) VarType {
+	return VarType{fmt.Sprintf("bytes%d", size), size}
+}
+
+// ParseVarType parses a variable type from its name, as returned by `VarType.String`.
+func ParseVarType(name string) (VarType, error) {
+	if t, ok := varTypes[name]; ok {
+		return t, nil
+	}
+
+	if strings.HasPrefix(name, "bytes") {
+		size, err := strconv.ParseUint(name[len("bytes"):], 10, 32)
+		if err == nil && strconv.FormatUint(size, 10) == name[len("bytes"):] {
+			return VarBytes(uint32(size)), nil
+		}
+	}
+
+	return VarType{}, fmt.Errorf("unknown variable type: `%v`", name)
+}
+
+// String returns the type's name, e.g., `u32` or `bytes32`.
+func (t VarType) String() string {
+	return t.name
+}
+
+// Size returns the size of the variable type, in bytes.
+func (t VarType) Size() uint32 {
+	return t.size
+}
+
+// MarshalText implements `encoding.TextMarshaler`, using the type's name.
+func (t VarType) MarshalText() ([]byte, error) {
+	return []byte(t.name), nil
+}
+
+// UnmarshalText implements `encoding.TextUnmarshaler`, accepting any name `ParseVarType` does.
+func (t *VarType) UnmarshalText(text []byte) error {
+	parsed, err := ParseVarType(string(text))
+	if err != nil {
+		return err
+	}
+
+	*t = parsed
+	return nil
+}
+
+// Decode decodes a variable's raw value, which must be of the type's size.
+// Booleans are decoded as `bool`, integers as the matching Go integer type (`amount` as `uint64`),
+// addresses as `Address` and raw bytes as `[]byte`. Integers are big-endian.
+func (t VarType) Decode(raw []byte) (interface{}, error) {
+	if uint32(len(raw)) != t.size {
+		return nil, fmt.Errorf("invalid %v value size; expected: %v bytes, given: %v", t, t.size, len(raw))
+	}
+
+	switch t {
+	case VarBool:
+		if raw[0] > 1 {
+			return nil, fmt.Errorf("invalid bool value: %v", raw[0])
+		}
+		return raw[0] == 1, nil
+	case VarI8:
+		return int8(raw[0]), nil
+	case VarU8:
+		return raw[0], nil
+	case VarI16:
+		return int16(binary.BigEndian.Uint16(raw)), nil
+	case VarU16:
+		return binary.BigEndian.Uint16(raw), nil
+	case VarI32:
+		return int32(binary.BigEndian.Uint32(raw)), nil
+	case VarU32:
+		return binary.BigEndian.Uint32(raw), nil
+	case VarI64:
+		return int64(binary.BigEndian.Uint64(raw)), nil
+	case VarU64, VarAmount:
+		return binary.BigEndian.Uint64(raw), nil
+	case VarAddress:
+		return BytesToAddress(raw), nil
+	default:
+		return append([]byte(nil), raw...), nil
+	}
+}
+
+// LayoutVar is a named, typed app storage variable.
+type LayoutVar struct {
+	ID   uint32  `json:"id"`
+	Name string  `json:"name"`
+	Type VarType `json:"type"`
+}
+
+// Layout describes an app's storage variables, ordered by their ids.
+// Unlike `DataLayout`, which only holds the variables' sizes,
+// it allows tooling to interpret app storage by variable name.
+//
+// A Layout is encoded as JSON, e.g.:
+//
+//	[{"id":0,"name":"counter","type":"u32"},{"id":1,"name":"owner","type":"address"}]
+type Layout []LayoutVar
+
+// DataLayout returns the variables' sizes, as expected by a `deploy template` transaction.
+func (l Layout) DataLayout() DataLayout {
+	dl := make(DataLayout, len(l))
+	for i, v := range l {
+		dl[i] = v.Type.size
+	}
+
+	return dl
+}
+
+// Encode encodes the variables' sizes, as expected by a `deploy template` transaction.
+func (l Layout) Encode() []byte {
+	return l.DataLayout().Encode()
+}
+
+// Var returns the variable named `name`, if any.
+func (l Layout) Var(name string) (LayoutVar, bool) {
+	for _, v := range l {
+		if v.Name == name {
+			return v, true
+		}
+	}
+
+	return LayoutVar{}, false
+}
+
+// DecodeVars decodes all the variables' values, by name.
+// `read` returns the raw value of a variable, given its id (see `ReadAppVars`).
+func (l Layout) DecodeVars(read func(varID uint32) ([]byte, error)) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(l))
+	for _, v := range l {
+		raw, err := read(v.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read `%v` variable: %w", v.Name, err)
+		}
+
+		if values[v.Name], err = v.Type.Decode(raw); err != nil {
+			return nil, fmt.Errorf("invalid `%v` variable: %v", v.Name, err)
+		}
+	}
+
+	return values, nil
+}
+
+// UnmarshalJSON decodes a Layout from JSON, validating it as `LayoutBuilder.Build` does.
+// Variables must be listed in the order of their ids.
+func (l *Layout) UnmarshalJSON(b []byte) error {
+	var vars []LayoutVar
+	if err := json.Unmarshal(b, &vars); err != nil {
+		return err
+	}
+
+	lb := NewLayoutBuilder()
+	for i, v := range vars {
+		if v.ID != uint32(i) {
+			return fmt.Errorf("invalid `%v` variable id; expected: %v, given: %v", v.Name, i, v.ID)
+		}
+		if lb = lb.Var(v.Name, v.Type); lb.err != nil {
+			return fmt.Errorf("invalid layout: %w", lb.err)
+		}
+	}
+
+	*l = lb.vars
+	return nil
+}
+
+// LayoutBuilder declares an app's storage variables, assigning them sequential ids:
+//
+//	layout, err := svm.NewLayoutBuilder().
+//	    Var("counter", svm.VarU32).
+//	    Var("owner", svm.VarAddress).
+//	    Build()
+type LayoutBuilder struct {
+	vars Layout
+
+	// err is the reason the first invalid variable can't be declared, if any.
+	// It is reported by `LayoutBuilder.Build`.
+	err error
+}
+
+// NewLayoutBuilder returns a builder with no variables declared.
+func NewLayoutBuilder() LayoutBuilder {
+	return LayoutBuilder{}
+}
+
+// Var declares a variable, whose id is the number of variables declared before it.
+// Each variable must have a unique, non-empty name, and a size of 1 to `KVValueSize` bytes.
+func (lb LayoutBuilder) Var(name string, t VarType) LayoutBuilder {
+	if lb.err != nil {
+		return lb
+	}
+
+	switch _, exists := lb.vars.Var(name); {
+	case name == "":
+		lb.err = fmt.Errorf("invalid variable #%v: missing name", len(lb.vars))
+	case exists:
+		lb.err = fmt.Errorf("invalid `%v` variable: duplicate name", name)
+	case t.size == 0 || t.size > KVValueSize:
+		lb.err = fmt.Errorf("invalid `%v` variable size; expected: 1 to %v bytes, given: %v", name, KVValueSize, t.size)
+	}
+	if lb.err != nil {
+		return lb
+	}
+
+	// Copying prevents builders derived from the same builder from sharing a backing array.
+	vars := make(Layout, len(lb.vars), len(lb.vars)+1)
+	copy(vars, lb.vars)
+	lb.vars = append(vars, LayoutVar{ID: uint32(len(vars)), Name: name, Type: t})
+	return lb
+}
+
+// Build returns the declared layout, or the first error reported by `Var`.
+func (lb LayoutBuilder) Build() (Layout, error) {
+	if lb.err != nil {
+		return nil, fmt.Errorf("failed to build layout: %w", lb.err)
+	}
+
+	return append(Layout{}, lb.vars...), nil
+}
@@ -0,0 +1,258 @@
+package svm
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"go-svm/codec"
+	"io/ioutil"
+	"testing"
+)
+
+func TestLayoutBuilder(t *testing.T) {
+	req := require.New(t)
+
+	layout, err := NewLayoutBuilder().
+		Var("counter", VarU32).
+		Var("owner", VarAddress).
+		Var("enabled", VarBool).
+		Var("hash", VarBytes(32)).
+		Build()
+	req.NoError(err)
+	req.Equal(Layout{
+		{ID: 0, Name: "counter", Type: VarU32},
+		{ID: 1, Name: "owner", Type: VarAddress},
+		{ID: 2, Name: "enabled", Type: VarBool},
+		{ID: 3, Name: "hash", Type: VarBytes(32)},
+	}, layout)
+	req.Equal(DataLayout{4, 20, 1, 32}, layout.DataLayout())
+	req.Equal(DataLayout{4, 20, 1, 32}.Encode(), layout.Encode())
+
+	owner, ok := layout.Var("owner")
+	req.True(ok)
+	req.Equal(uint32(1), owner.ID)
+	_, ok = layout.Var("missing")
+	req.False(ok)
+
+	layout, err = NewLayoutBuilder().Build()
+	req.NoError(err)
+	req.Empty(layout)
+}
+
+func TestLayoutBuilder_Branching(t *testing.T) {
+	req := require.New(t)
+
+	base := NewLayoutBuilder().Var("counter", VarU32)
+	a, err := base.Var("a", VarU8).Build()
+	req.NoError(err)
+	b, err := base.Var("b", VarU64).Build()
+	req.NoError(err)
+
+	req.Equal("a", a[1].Name)
+	req.Equal("b", b[1].Name)
+}
+
+func TestLayoutBuilder_Invalid(t *testing.T) {
+	cases := []struct {
+		lb  LayoutBuilder
+		err string
+	}{
+		{NewLayoutBuilder().Var("", VarU32), "failed to build layout: invalid variable #0: missing name"},
+		{NewLayoutBuilder().Var("counter", VarU32).Var("counter", VarU64),
+			"failed to build layout: invalid `counter` variable: duplicate name"},
+		{NewLayoutBuilder().Var("empty", VarBytes(0)),
+			"failed to build layout: invalid `empty` variable size; expected: 1 to 32 bytes, given: 0"},
+		{NewLayoutBuilder().Var("big", VarBytes(33)),
+			"failed to build layout: invalid `big` variable size; expected: 1 to 32 bytes, given: 33"},
+		{NewLayoutBuilder().Var("zero", VarType{}),
+			"failed to build layout: invalid `zero` variable size; expected: 1 to 32 bytes, given: 0"},
+		// Only the first error is reported.
+		{NewLayoutBuilder().Var("", VarU32).Var("big", VarBytes(33)), "failed to build layout: invalid variable #0: missing name"},
+	}
+
+	for _, c := range cases {
+		_, err := c.lb.Build()
+		require.EqualError(t, err, c.err)
+	}
+}
+
+func TestParseVarType(t *testing.T) {
+	for _, vt := range []VarType{VarBool, VarI8, VarU8, VarI16, VarU16, VarI32, VarU32, VarI64, VarU64, VarAmount, VarAddress, VarBytes(1), VarBytes(32)} {
+		parsed, err := ParseVarType(vt.String())
+		require.NoError(t, err, vt)
+		require.Equal(t, vt, parsed)
+	}
+
+	for _, name := range []string{"", "u128", "U32", "bytes", "bytes-1", "bytes+1", "bytes01", "bytes4294967296"} {
+		_, err := ParseVarType(name)
+		require.EqualError(t, err, "unknown variable type: `"+name+"`")
+	}
+}
+
+func TestLayout_JSON(t *testing.T) {
+	req := require.New(t)
+
+	layout, err := NewLayoutBuilder().Var("counter", VarU32).Var("owner", VarAddress).Build()
+	req.NoError(err)
+
+	b, err := json.Marshal(layout)
+	req.NoError(err)
+	req.Equal(`[{"id":0,"name":"counter","type":"u32"},{"id":1,"name":"owner","type":"address"}]`, string(b))
+
+	var decoded Layout
+	req.NoError(json.Unmarshal(b, &decoded))
+	req.Equal(layout, decoded)
+
+	cases := []struct {
+		json string
+		err  string
+	}{
+		{`[{"id":1,"name":"counter","type":"u32"}]`, "invalid `counter` variable id; expected: 0, given: 1"},
+		{`[{"id":0,"name":"counter","type":"u128"}]`, "unknown variable type: `u128`"},
+		{`[{"id":0,"name":"hash","type":"bytes64"}]`, "invalid layout: invalid `hash` variable size; expected: 1 to 32 bytes, given: 64"},
+		{`[{"id":0,"name":"a","type":"u8"},{"id":1,"name":"a","type":"u8"}]`, "invalid layout: invalid `a` variable: duplicate name"},
+	}
+
+	for _, c := range cases {
+		require.EqualError(t, json.Unmarshal([]byte(c.json), &decoded), c.err, c.json)
+	}
+}
+
+func TestVarType_Decode(t *testing.T) {
+	addr := BytesToAddress([]byte{0xbc, 0x21, 0x3f, 0xfe})
+
+	cases := []struct {
+		t     VarType
+		raw   []byte
+		value interface{}
+	}{
+		{VarBool, []byte{0}, false},
+		{VarBool, []byte{1}, true},
+		{VarI8, []byte{0xff}, int8(-1)},
+		{VarU8, []byte{0xff}, uint8(255)},
+		{VarI16, []byte{0xff, 0xfe}, int16(-2)},
+		{VarU16, []byte{0x01, 0x2c}, uint16(300)},
+		{VarI32, []byte{0xff, 0xff, 0xff, 0xfb}, int32(-5)},
+		{VarU32, []byte{0, 0, 0, 10}, uint32(10)},
+		{VarI64, []byte{0x80, 0, 0, 0, 0, 0, 0, 0}, int64(-1 << 63)},
+		{VarU64, []byte{0, 0, 1, 0, 0, 0, 0, 0}, uint64(1 << 40)},
+		{VarAmount, []byte{0, 0, 0, 0, 0, 0, 0, 5}, uint64(5)},
+		{VarAddress, addr[:], addr},
+		{VarBytes(3), []byte{1, 2, 3}, []byte{1, 2, 3}},
+	}
+
+	for _, c := range cases {
+		value, err := c.t.Decode(c.raw)
+		require.NoError(t, err, c.t)
+		require.Equal(t, c.value, value, c.t)
+	}
+
+	_, err := VarU32.Decode([]byte{0, 0, 10})
+	require.EqualError(t, err, "invalid u32 value size; expected: 4 bytes, given: 3")
+	_, err = VarBool.Decode([]byte{2})
+	require.EqualError(t, err, "invalid bool value: 2")
+}
+
+func TestLayout_DecodeVars(t *testing.T) {
+	req := require.New(t)
+
+	layout, err := NewLayoutBuilder().Var("counter", VarU32).Var("owner", VarAddress).Build()
+	req.NoError(err)
+
+	owner := BytesToAddress([]byte{0xbc, 0x21, 0x3f, 0xfe})
+	storage := map[uint32][]byte{0: {0, 0, 0, 15}, 1: owner[:]}
+	read := func(varID uint32) ([]byte, error) {
+		raw, ok := storage[varID]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return raw, nil
+	}
+
+	values, err := layout.DecodeVars(read)
+	req.NoError(err)
+	req.Equal(map[string]interface{}{"counter": uint32(15), "owner": owner}, values)
+
+	delete(storage, 1)
+	_, err = layout.DecodeVars(read)
+	req.EqualError(err, "failed to read `owner` variable: not found")
+
+	storage[1] = owner[:4]
+	_, err = layout.DecodeVars(read)
+	req.EqualError(err, "invalid `owner` variable: invalid address value size; expected: 20 bytes, given: 4")
+}
+
+// TestVarType_Decode_CounterStorage verifies the variables' byte order against
+// the counter app's storage, as written by SVM.
+func TestVarType_Decode_CounterStorage(t *testing.T) {
+	req := require.New(t)
+
+	code, err := ioutil.ReadFile(counterTemplateFilename)
+	req.NoError(err)
+
+	imports := newCounterImports(t)
+	defer imports.Free()
+
+	kv, err := NewStateKV_FFIFrom(NewInMemoryStateKV())
+	req.NoError(err)
+	defer kv.Free()
+
+	runtime, err := NewRuntimeBuilder().
+		WithImports(imports).
+		WithStateKV_FFI(kv).
+		Build()
+	req.NoError(err)
+	defer runtime.Free()
+
+	layout, err := NewLayoutBuilder().Var("counter", VarU32).Build()
+	req.NoError(err)
+
+	tx, err := codec.EncodeTxDeployTemplate(0, "counter", code, layout.Encode())
+	req.NoError(err)
+	deployReceipt, err := DeployTemplate(runtime, tx, Address{}, false, 0)
+	req.NoError(err)
+
+	const initial = 0x01020304
+	calldata, err := codec.EncodeCallDataValues(codec.U32(initial))
+	req.NoError(err)
+	tx, err = codec.EncodeTxSpawnApp(0, deployReceipt.TemplateAddr[:], "counter", "initialize", calldata)
+	req.NoError(err)
+	spawnReceipt, err := SpawnApp(runtime, tx, Address{}, false, 0)
+	req.NoError(err)
+	req.True(spawnReceipt.Success)
+
+	raw, err := ReadAppVar(runtime, spawnReceipt.AppAddr, spawnReceipt.State, 0)
+	req.NoError(err)
+	req.Len(raw, KVValueSize)
+
+	be := make([]byte, 4)
+	binary.BigEndian.PutUint32(be, initial)
+	req.Equal(be, raw[:4])
+
+	counter, err := VarU32.Decode(raw[:4])
+	req.NoError(err)
+	req.Equal(uint32(initial), counter)
+
+	calldata, err = codec.EncodeCallDataValues(codec.U32(5))
+	req.NoError(err)
+	tx, err = codec.EncodeTxExecApp(0, spawnReceipt.AppAddr[:], "counter_add", calldata)
+	req.NoError(err)
+	execReceipt, err := ExecApp(runtime, tx, spawnReceipt.State, false, 0)
+	req.NoError(err)
+	req.True(execReceipt.Success)
+
+	// Both the current and the past states can be read.
+	values, err := ReadAppVars(runtime, spawnReceipt.AppAddr, execReceipt.NewState, layout)
+	req.NoError(err)
+	req.Equal(map[string]interface{}{"counter": uint32(initial + 5)}, values)
+
+	values, err = ReadAppVars(runtime, spawnReceipt.AppAddr, spawnReceipt.State, layout)
+	req.NoError(err)
+	req.Equal(map[string]interface{}{"counter": uint32(initial)}, values)
+
+	// Variables which were never set are zero.
+	raw, err = ReadAppVar(runtime, spawnReceipt.AppAddr, spawnReceipt.State, 1)
+	req.NoError(err)
+	req.Equal(make([]byte, KVValueSize), raw)
+}
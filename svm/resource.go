@@ -0,0 +1,76 @@
+package svm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Resource represents the number of live, manually-allocated
+// SVM resources of a particular type.
+type Resource struct {
+	TypeName string
+	Count    int
+}
+
+// TotalLiveResources returns the total number of live, manually-allocated SVM resources.
+func TotalLiveResources() int {
+	return cSvmTotalLiveResources()
+}
+
+// LiveResources returns the live, manually-allocated SVM resources, grouped by type.
+func LiveResources() []Resource {
+	return cSvmResources()
+}
+
+// TestingT is the subset of `testing.TB` used by AssertNoLeaks.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertNoLeaks takes a snapshot of the live SVM resources, and returns a function
+// which compares it against a second snapshot, reporting any type that has more
+// live resources than before. It is intended to be deferred at the beginning of a test:
+//
+//     defer svm.AssertNoLeaks(t)()
+//
+func AssertNoLeaks(t TestingT) func() {
+	before := LiveResources()
+
+	return func() {
+		t.Helper()
+
+		if leaks := diffResources(before, LiveResources()); len(leaks) > 0 {
+			desc := make([]string, len(leaks))
+			for i, r := range leaks {
+				desc[i] = fmt.Sprintf("%v: %v", r.TypeName, r.Count)
+			}
+			t.Errorf("go-svm: leaked resources: %v", strings.Join(desc, ", "))
+		}
+	}
+}
+
+// diffResources returns the resources which their count in `after`
+// exceeds their count in `before`, along with the count difference.
+func diffResources(before, after []Resource) []Resource {
+	counts := make(map[string]int)
+	for _, r := range before {
+		counts[r.TypeName] -= r.Count
+	}
+	for _, r := range after {
+		counts[r.TypeName] += r.Count
+	}
+
+	diff := make([]Resource, 0)
+	for typeName, count := range counts {
+		if count > 0 {
+			diff = append(diff, Resource{TypeName: typeName, Count: count})
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].TypeName < diff[j].TypeName
+	})
+
+	return diff
+}
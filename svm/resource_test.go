@@ -0,0 +1,74 @@
+package svm
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type mockT struct {
+	errors []string
+}
+
+func (t *mockT) Helper() {}
+
+func (t *mockT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestDiffResources(t *testing.T) {
+	req := require.New(t)
+
+	before := []Resource{
+		{TypeName: "svm_byte_array", Count: 2},
+		{TypeName: "Runtime", Count: 1},
+		{TypeName: "Imports", Count: 1},
+	}
+	after := []Resource{
+		{TypeName: "svm_byte_array", Count: 5},
+		{TypeName: "Runtime", Count: 1},
+		{TypeName: "StateKV", Count: 1},
+	}
+
+	req.Equal([]Resource{
+		{TypeName: "StateKV", Count: 1},
+		{TypeName: "svm_byte_array", Count: 3},
+	}, diffResources(before, after))
+
+	req.Empty(diffResources(after, after))
+	req.Empty(diffResources(after, after[1:]))
+}
+
+func TestAssertNoLeaks(t *testing.T) {
+	req := require.New(t)
+
+	mt := &mockT{}
+	AssertNoLeaks(mt)()
+	req.Empty(mt.errors)
+
+	imports := newCounterImports(t)
+	mt = &mockT{}
+	check := AssertNoLeaks(mt)
+	imports.Free()
+	check()
+	req.Empty(mt.errors)
+}
+
+func TestAssertNoLeaks_Leak(t *testing.T) {
+	req := require.New(t)
+
+	mt := &mockT{}
+	check := AssertNoLeaks(mt)
+	before := LiveResources()
+	imports := newCounterImports(t)
+	leaked := diffResources(before, LiveResources())
+	check()
+	imports.Free()
+
+	req.NotEmpty(leaked)
+	req.Len(mt.errors, 1)
+	req.Contains(mt.errors[0], "go-svm: leaked resources: ")
+	for _, r := range leaked {
+		req.Contains(mt.errors[0], fmt.Sprintf("%v: %v", r.TypeName, r.Count))
+	}
+}
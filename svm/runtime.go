@@ -9,6 +9,12 @@ import (
 type Runtime struct {
 	// _inner is a pointer to an SVM-managed heap allocation.
 	_inner unsafe.Pointer
+
+	// apps records the template of each app spawned by the runtime.
+	apps *appTemplates
+
+	// kv is the runtime's FFI state KV, if any, through which app storage is read.
+	kv *StateKV_FFI
 }
 
 func (r Runtime) Free() {
@@ -19,6 +25,13 @@ type RuntimeBuilder struct {
 	imports unsafe.Pointer
 	kv      unsafe.Pointer
 	host    unsafe.Pointer
+
+	// ffiKV is the configured FFI state KV, if any.
+	ffiKV *StateKV_FFI
+
+	// kvPath is the directory of a disk-persistent KV.
+	// If empty, the runtime is backed by the configured state KV.
+	kvPath string
 }
 
 func NewRuntimeBuilder() RuntimeBuilder {
@@ -32,24 +45,49 @@ func (rb RuntimeBuilder) WithImports(imports *Imports) RuntimeBuilder {
 
 func (rb RuntimeBuilder) WithStateKV_Mem(kv *StateKV_Mem) RuntimeBuilder {
 	rb.kv = kv._inner
+	rb.ffiKV = nil
 	return rb
 }
 
 func (rb RuntimeBuilder) WithStateKV_FFI(kv *StateKV_FFI) RuntimeBuilder {
 	rb.kv = kv._inner
+	rb.ffiKV = kv
+	return rb
+}
+
+// WithKVPath configures the runtime to persist its state on disk, under the given directory.
+// It can't be used together with an in-memory or FFI state KV.
+func (rb RuntimeBuilder) WithKVPath(dir string) RuntimeBuilder {
+	rb.kvPath = dir
 	return rb
 }
 
 func (rb RuntimeBuilder) Build() (Runtime, error) {
 	var p unsafe.Pointer
 
+	if rb.kvPath != "" {
+		if rb.kv != nil {
+			return Runtime{}, fmt.Errorf("failed to create runtime: both a state KV and a KV path were given")
+		}
+
+		if err := cSvmRuntimeCreate(
+			&p,
+			rb.kvPath,
+			rb.imports,
+		); err != nil {
+			return Runtime{}, fmt.Errorf("failed to create runtime: %w", err)
+		}
+
+		return Runtime{p, newAppTemplates(), nil}, nil
+	}
+
 	if err := cSvmMemoryRuntimeCreate(
 		&p,
 		rb.kv,
 		rb.imports,
 	); err != nil {
-		return Runtime{}, fmt.Errorf("failed to create runtime: %v", err)
+		return Runtime{}, fmt.Errorf("failed to create runtime: %w", err)
 	}
 
-	return Runtime{p}, nil
+	return Runtime{p, newAppTemplates(), rb.ffiKV}, nil
 }
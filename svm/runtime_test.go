@@ -0,0 +1,73 @@
+package svm
+
+import (
+	"github.com/stretchr/testify/require"
+	"go-svm/codec"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+const counterTemplateFilename = "../examples/counter/wasm/counter.wasm"
+
+func newCounterImports(t *testing.T) *Imports {
+	binaryOp := func(op func(a, b int32) int32) hostFunction {
+		return func(args []Value) ([]Value, error) {
+			return []Value{I32(op(args[0].ToI32(), args[1].ToI32()))}, nil
+		}
+	}
+
+	imports, err := NewImportsBuilder().
+		RegisterFunction("add", ValueTypes{TypeI32, TypeI32}, ValueTypes{TypeI32},
+			binaryOp(func(a, b int32) int32 { return a + b })).
+		RegisterFunction("mul", ValueTypes{TypeI32, TypeI32}, ValueTypes{TypeI32},
+			binaryOp(func(a, b int32) int32 { return a * b })).
+		Build()
+	require.NoError(t, err)
+
+	return imports
+}
+
+func TestRuntimeBuilder_WithKVPath_Reopen(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "go-svm-kv")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	code, err := ioutil.ReadFile(counterTemplateFilename)
+	req.NoError(err)
+
+	// Deploy the template using the first runtime.
+	imports := newCounterImports(t)
+	defer imports.Free()
+
+	runtime, err := NewRuntimeBuilder().
+		WithImports(imports).
+		WithKVPath(dir).
+		Build()
+	req.NoError(err)
+
+	tx, err := codec.EncodeTxDeployTemplate(0, "counter", code, DataLayout{4}.Encode())
+	req.NoError(err)
+	deployReceipt, err := DeployTemplate(runtime, tx, Address{}, false, 0)
+	req.NoError(err)
+	req.True(deployReceipt.Success)
+	runtime.Free()
+
+	// Spawn an app out of the template using a fresh runtime over the same directory.
+	runtime, err = NewRuntimeBuilder().
+		WithImports(imports).
+		WithKVPath(dir).
+		Build()
+	req.NoError(err)
+	defer runtime.Free()
+
+	calldata, err := codec.EncodeCallData([]string{"u32"}, []int{10})
+	req.NoError(err)
+	tx, err = codec.EncodeTxSpawnApp(0, deployReceipt.TemplateAddr[:], "counter", "initialize", calldata)
+	req.NoError(err)
+	spawnReceipt, err := SpawnApp(runtime, tx, Address{}, false, 0)
+	req.NoError(err)
+	req.True(spawnReceipt.Success)
+}
@@ -0,0 +1,52 @@
+package svm
+
+// StateKV is the contract of a Go-implemented state KV store,
+// to be used by SVM through an FFI state KV (see `NewStateKV_FFIFrom`).
+//
+// Implementations must copy the `key` and `value` slices given to them if they
+// retain them, since these are aliases to SVM-managed buffers.
+type StateKV interface {
+	// Get returns the value stored under `key`, including uncommitted changes.
+	// It returns nil if `key` isn't found, or a value of size `KVValueSize` otherwise.
+	Get(key []byte) []byte
+
+	// Set stores `value` under `key`, as an uncommitted change.
+	Set(key, value []byte)
+
+	// Discard rolls back all the uncommitted changes.
+	Discard()
+
+	// Checkpoint commits all the uncommitted changes,
+	// and returns the new state root, of size `StateSize`.
+	Checkpoint() []byte
+
+	// Head returns the current state root, of size `StateSize`.
+	Head() []byte
+}
+
+// HistoricalStateKV is a StateKV which can also read past committed states,
+// allowing app storage to be read at any state (see `ReadAppVar`).
+type HistoricalStateKV interface {
+	StateKV
+
+	// GetAt returns the value stored under `key` at the committed state `state`.
+	// It returns nil if `key` isn't found, and an error if `state` is unknown.
+	GetAt(state, key []byte) ([]byte, error)
+}
+
+// NewStateKV_FFIFrom creates a new FFI state KV, backed by the given StateKV implementation.
+func NewStateKV_FFIFrom(kv StateKV) (*StateKV_FFI, error) {
+	ffi, err := NewStateKV_FFI()
+	if err != nil {
+		return nil, err
+	}
+
+	ffi.RegisterGet(kv.Get)
+	ffi.RegisterSet(kv.Set)
+	ffi.RegisterDiscard(kv.Discard)
+	ffi.RegisterCheckpoint(kv.Checkpoint)
+	ffi.RegisterHead(kv.Head)
+	ffi.backend = kv
+
+	return ffi, nil
+}
@@ -3,26 +3,23 @@ package svm
 // #include <stdint.h>
 // #include "./svm.h"
 //
-// extern void kv_get(uint8_t*, uint32_t, uint8_t*, uint32_t*);
-// extern void kv_set(uint8_t*, uint32_t, uint8_t*, uint32_t);
-// extern void kv_discard();
-// extern void kv_checkpoint(uint8_t*);
-// extern void kv_head(uint8_t*);
+// extern svm_result_t svm_ffi_state_kv_create_slot(void **state_kv, uint32_t slot);
 //
 import "C"
 import (
 	"fmt"
 	"runtime"
+	"sync"
 	"unsafe"
 )
 
 //export kv_get
-func kv_get(keyPtr *C.uint8_t, keyLen C.uint32_t, valuePtr *C.uint8_t, valueLen *C.uint32_t) {
+func kv_get(slot C.uint32_t, keyPtr *C.uint8_t, keyLen C.uint32_t, valuePtr *C.uint8_t, valueLen *C.uint32_t) {
 	// Create []byte slice alias to the key.
 	keyAlias := NewCBytes(unsafe.Pointer(keyPtr), int(keyLen)).GoBytesAlias()
 
 	// Invoke handler.
-	f := kvHandlers.get
+	f := kvHandlersStore.get(uint32(slot)).get
 	if f == nil {
 		panic("go-svm: `get` handler wasn't registered for FFI state KV")
 	}
@@ -48,13 +45,13 @@ func kv_get(keyPtr *C.uint8_t, keyLen C.uint32_t, valuePtr *C.uint8_t, valueLen
 }
 
 //export kv_set
-func kv_set(keyPtr *C.uint8_t, keyLen C.uint32_t, valuePtr *C.uint8_t, valueLen C.uint32_t) {
+func kv_set(slot C.uint32_t, keyPtr *C.uint8_t, keyLen C.uint32_t, valuePtr *C.uint8_t, valueLen C.uint32_t) {
 	// Create []byte slice aliases.
 	keyAlias := NewCBytes(unsafe.Pointer(keyPtr), int(keyLen)).GoBytesAlias()
 	valueAlias := NewCBytes(unsafe.Pointer(valuePtr), int(valueLen)).GoBytesAlias()
 
 	// Invoke handler.
-	f := kvHandlers.set
+	f := kvHandlersStore.get(uint32(slot)).set
 	if f == nil {
 		panic("go-svm: `set` handler wasn't registered for FFI state KV")
 	}
@@ -62,8 +59,8 @@ func kv_set(keyPtr *C.uint8_t, keyLen C.uint32_t, valuePtr *C.uint8_t, valueLen
 }
 
 //export kv_discard
-func kv_discard() {
-	f := kvHandlers.discard
+func kv_discard(slot C.uint32_t) {
+	f := kvHandlersStore.get(uint32(slot)).discard
 	if f == nil {
 		panic("go-svm: `discard` handler wasn't registered for FFI state KV")
 	}
@@ -71,8 +68,8 @@ func kv_discard() {
 }
 
 //export kv_checkpoint
-func kv_checkpoint(statePtr *C.uint8_t) {
-	f := kvHandlers.checkpoint
+func kv_checkpoint(slot C.uint32_t, statePtr *C.uint8_t) {
+	f := kvHandlersStore.get(uint32(slot)).checkpoint
 	if f == nil {
 		panic("go-svm: `checkpoint` handler wasn't registered for FFI state KV")
 	}
@@ -89,8 +86,8 @@ func kv_checkpoint(statePtr *C.uint8_t) {
 }
 
 //export kv_head
-func kv_head(headPtr *C.uint8_t) {
-	f := kvHandlers.head
+func kv_head(slot C.uint32_t, headPtr *C.uint8_t) {
+	f := kvHandlersStore.get(uint32(slot)).head
 	if f == nil {
 		panic("go-svm: `head` handler wasn't registered for FFI state KV")
 	}
@@ -106,61 +103,146 @@ func kv_head(headPtr *C.uint8_t) {
 	runtime.KeepAlive(result)
 }
 
-func cSvmFFIStateKVCreate(p *unsafe.Pointer) cSvmResultT {
-	return (cSvmResultT)(C.svm_ffi_state_kv_create(
-		p,
-		(*[0]byte)(C.kv_get),
-		(*[0]byte)(C.kv_set),
-		(*[0]byte)(C.kv_discard),
-		(*[0]byte)(C.kv_checkpoint),
-		(*[0]byte)(C.kv_head),
-	))
+func cSvmFFIStateKVCreate(p *unsafe.Pointer, slot uint32) cSvmResultT {
+	return (cSvmResultT)(C.svm_ffi_state_kv_create_slot(p, C.uint32_t(slot)))
 }
 
-// kvHandlers is a static container for the KV-ops handlers, written in Go,
-// to be invoked from the unsafe, cgo-exported handlers.
-var kvHandlers = struct {
+// maxStateKV_FFI is the maximum number of FFI state KV instances which can be live at the same time.
+// It must match the number of slots defined in `state_kv_ffi_slots.c`.
+const maxStateKV_FFI = 16
+
+// kvHandlers holds the KV-ops handlers, written in Go, of a single FFI state KV instance.
+type kvHandlers struct {
 	get        func([]byte) []byte
 	set        func([]byte, []byte)
 	discard    func()
 	checkpoint func() []byte
 	head       func() []byte
-}{}
+}
+
+// kvHandlersStore is a static container for the KV-ops handlers of each live FFI state KV instance,
+// indexed by the instance slot, to be invoked from the unsafe, cgo-exported handlers.
+var kvHandlersStore = kvHandlersSlots{}
+
+type kvHandlersSlots struct {
+	sync.RWMutex
+	slots [maxStateKV_FFI]*kvHandlers
+}
+
+// alloc assigns a free slot to a new FFI state KV instance.
+func (s *kvHandlersSlots) alloc() (uint32, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	for i, handlers := range s.slots {
+		if handlers == nil {
+			s.slots[i] = &kvHandlers{}
+			return uint32(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("too many live FFI state KV instances; max: %v", maxStateKV_FFI)
+}
+
+// release frees the slot of an FFI state KV instance, so that it could be re-assigned.
+func (s *kvHandlersSlots) release(slot uint32) {
+	s.Lock()
+	defer s.Unlock()
+
+	if slot < maxStateKV_FFI {
+		s.slots[slot] = nil
+	}
+}
+
+// get returns a copy of the handlers registered for the slot.
+func (s *kvHandlersSlots) get(slot uint32) kvHandlers {
+	s.RLock()
+	defer s.RUnlock()
+
+	if slot >= maxStateKV_FFI || s.slots[slot] == nil {
+		panic(fmt.Sprintf("go-svm: FFI state KV slot isn't allocated; slot: %v", slot))
+	}
+
+	return *s.slots[slot]
+}
+
+// update applies a change to the handlers registered for the slot.
+func (s *kvHandlersSlots) update(slot uint32, f func(*kvHandlers)) {
+	s.Lock()
+	defer s.Unlock()
+
+	if slot >= maxStateKV_FFI || s.slots[slot] == nil {
+		panic(fmt.Sprintf("go-svm: FFI state KV slot isn't allocated; slot: %v", slot))
+	}
+
+	f(s.slots[slot])
+}
 
 type StateKV_FFI struct {
 	// _inner is a pointer to an SVM-managed heap allocation.
+	// It is nil once the instance is freed.
 	_inner unsafe.Pointer
+
+	// slot is the index of the instance KV-ops handlers in `kvHandlersStore`.
+	slot uint32
+
+	// backend is the StateKV implementation the instance was created from, if any.
+	backend StateKV
 }
 
-func NewStateKV_FFI() (StateKV_FFI, error) {
+// NewStateKV_FFI creates a new FFI state KV, whose KV-ops handlers are registered
+// with its `Register*` methods.
+//
+// SVM's FFI state KV callbacks don't carry any user data, so each instance is assigned
+// one of a fixed set of callbacks (see `state_kv_ffi_slots.c`). Thus, at most 16 instances
+// can be live at the same time; freeing an instance allows creating another one.
+func NewStateKV_FFI() (*StateKV_FFI, error) {
+	slot, err := kvHandlersStore.alloc()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FFI state KV store: %v", err)
+	}
+
 	var p unsafe.Pointer
-	if res := cSvmFFIStateKVCreate(&p); res != cSvmSuccess {
-		return StateKV_FFI{}, fmt.Errorf("failed to create FFI state KV store")
+	if res := cSvmFFIStateKVCreate(&p, slot); res != cSvmSuccess {
+		kvHandlersStore.release(slot)
+		return nil, fmt.Errorf("failed to create FFI state KV store")
 	}
 
-	return StateKV_FFI{p}, nil
+	return &StateKV_FFI{_inner: p, slot: slot}, nil
 }
 
-func (StateKV_FFI) RegisterGet(f func([]byte) []byte) {
-	kvHandlers.get = f
+func (kv *StateKV_FFI) RegisterGet(f func([]byte) []byte) {
+	kvHandlersStore.update(kv.slot, func(h *kvHandlers) { h.get = f })
 }
 
-func (StateKV_FFI) RegisterSet(f func([]byte, []byte)) {
-	kvHandlers.set = f
+func (kv *StateKV_FFI) RegisterSet(f func([]byte, []byte)) {
+	kvHandlersStore.update(kv.slot, func(h *kvHandlers) { h.set = f })
 }
 
-func (StateKV_FFI) RegisterDiscard(f func()) {
-	kvHandlers.discard = f
+func (kv *StateKV_FFI) RegisterDiscard(f func()) {
+	kvHandlersStore.update(kv.slot, func(h *kvHandlers) { h.discard = f })
 }
 
-func (StateKV_FFI) RegisterCheckpoint(f func() []byte) {
-	kvHandlers.checkpoint = f
+func (kv *StateKV_FFI) RegisterCheckpoint(f func() []byte) {
+	kvHandlersStore.update(kv.slot, func(h *kvHandlers) { h.checkpoint = f })
 }
 
-func (StateKV_FFI) RegisterHead(f func() []byte) {
-	kvHandlers.head = f
+func (kv *StateKV_FFI) RegisterHead(f func() []byte) {
+	kvHandlersStore.update(kv.slot, func(h *kvHandlers) { h.head = f })
 }
 
-func (kv StateKV_FFI) Free() {
+// Free releases the instance, along with its slot. Freeing an instance more than once,
+// or freeing a nil or zero-value instance, is a no-op.
+func (kv *StateKV_FFI) Free() {
+	if kv == nil || kv._inner == nil {
+		return
+	}
+
 	cSvmStateKVDestroy(kv._inner)
+	kvHandlersStore.release(kv.slot)
+
+	// The slot may be re-assigned to another instance,
+	// so registering handlers on a freed instance must fail.
+	kv._inner = nil
+	kv.slot = maxStateKV_FFI
 }
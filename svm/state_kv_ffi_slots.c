@@ -0,0 +1,77 @@
+#include <stdint.h>
+#include "svm.h"
+#include "_cgo_export.h"
+
+// SVM's FFI state KV callbacks don't carry any user data, so in order to
+// support multiple independent FFI state KV instances, each instance is
+// assigned a slot with its own set of callbacks. Each callback forwards
+// the slot index to the respective Go handler, which dispatches it to the
+// handlers registered for that instance.
+//
+// The number of slots must match `maxStateKV_FFI` (see `state_kv_ffi.go`).
+
+typedef struct {
+	void (*get)(const uint8_t*, uint32_t, uint8_t*, uint32_t*);
+	void (*set)(const uint8_t*, uint32_t, const uint8_t*, uint32_t);
+	void (*discard)(void);
+	void (*checkpoint)(uint8_t*);
+	void (*head)(uint8_t*);
+} kv_slot_t;
+
+#define KV_SLOT(n)                                                                                      \
+	static void kv_get_##n(const uint8_t *key, uint32_t key_len, uint8_t *value, uint32_t *value_len) { \
+		kv_get(n, (uint8_t*)key, key_len, value, value_len);                                            \
+	}                                                                                                   \
+	static void kv_set_##n(const uint8_t *key, uint32_t key_len, const uint8_t *value, uint32_t value_len) { \
+		kv_set(n, (uint8_t*)key, key_len, (uint8_t*)value, value_len);                                  \
+	}                                                                                                   \
+	static void kv_discard_##n(void) { kv_discard(n); }                                                 \
+	static void kv_checkpoint_##n(uint8_t *state) { kv_checkpoint(n, state); }                          \
+	static void kv_head_##n(uint8_t *state) { kv_head(n, state); }
+
+#define KV_SLOT_ENTRY(n) { kv_get_##n, kv_set_##n, kv_discard_##n, kv_checkpoint_##n, kv_head_##n }
+
+KV_SLOT(0)
+KV_SLOT(1)
+KV_SLOT(2)
+KV_SLOT(3)
+KV_SLOT(4)
+KV_SLOT(5)
+KV_SLOT(6)
+KV_SLOT(7)
+KV_SLOT(8)
+KV_SLOT(9)
+KV_SLOT(10)
+KV_SLOT(11)
+KV_SLOT(12)
+KV_SLOT(13)
+KV_SLOT(14)
+KV_SLOT(15)
+
+static const kv_slot_t kv_slots[] = {
+	KV_SLOT_ENTRY(0),
+	KV_SLOT_ENTRY(1),
+	KV_SLOT_ENTRY(2),
+	KV_SLOT_ENTRY(3),
+	KV_SLOT_ENTRY(4),
+	KV_SLOT_ENTRY(5),
+	KV_SLOT_ENTRY(6),
+	KV_SLOT_ENTRY(7),
+	KV_SLOT_ENTRY(8),
+	KV_SLOT_ENTRY(9),
+	KV_SLOT_ENTRY(10),
+	KV_SLOT_ENTRY(11),
+	KV_SLOT_ENTRY(12),
+	KV_SLOT_ENTRY(13),
+	KV_SLOT_ENTRY(14),
+	KV_SLOT_ENTRY(15),
+};
+
+svm_result_t svm_ffi_state_kv_create_slot(void **state_kv, uint32_t slot) {
+	if (slot >= sizeof(kv_slots) / sizeof(kv_slots[0])) {
+		return SVM_FAILURE;
+	}
+
+	const kv_slot_t *s = &kv_slots[slot];
+	return svm_ffi_state_kv_create(state_kv, s->get, s->set, s->discard, s->checkpoint, s->head);
+}
@@ -0,0 +1,201 @@
+package svm
+
+import (
+	"crypto/sha256"
+	"github.com/stretchr/testify/require"
+	"go-svm/codec"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+func TestKVHandlersSlots(t *testing.T) {
+	req := require.New(t)
+
+	store := kvHandlersSlots{}
+	slots := make([]uint32, maxStateKV_FFI)
+	for i := range slots {
+		slot, err := store.alloc()
+		req.NoError(err)
+		req.Equal(uint32(i), slot)
+		slots[i] = slot
+	}
+
+	_, err := store.alloc()
+	req.EqualError(err, "too many live FFI state KV instances; max: 16")
+
+	// Handlers of different slots are isolated.
+	store.update(slots[0], func(h *kvHandlers) { h.discard = func() {} })
+	req.NotNil(store.get(slots[0]).discard)
+	req.Nil(store.get(slots[1]).discard)
+
+	// Released slots are re-assigned with no handlers.
+	store.release(slots[0])
+	req.Panics(func() { store.get(slots[0]) })
+	req.Panics(func() { store.update(slots[0], func(*kvHandlers) {}) })
+
+	// Out-of-range slots are never allocated.
+	req.Panics(func() { store.get(maxStateKV_FFI) })
+	req.Panics(func() { store.update(maxStateKV_FFI, func(*kvHandlers) {}) })
+	req.NotPanics(func() { store.release(maxStateKV_FFI) })
+
+	slot, err := store.alloc()
+	req.NoError(err)
+	req.Equal(slots[0], slot)
+	req.Nil(store.get(slot).discard)
+}
+
+func TestStateKV_FFI_Free(t *testing.T) {
+	req := require.New(t)
+
+	// Freeing a nil or zero-value instance doesn't release any slot.
+	var zero StateKV_FFI
+	req.NotPanics(zero.Free)
+	req.NotPanics((*StateKV_FFI)(nil).Free)
+
+	kv, err := NewStateKV_FFI()
+	req.NoError(err)
+	other, err := NewStateKV_FFI()
+	req.NoError(err)
+	defer other.Free()
+
+	kv.Free()
+	kv.Free()
+	req.Panics(func() { kv.RegisterDiscard(func() {}) })
+
+	// The other instance's slot is still allocated.
+	req.NotPanics(func() { other.RegisterDiscard(func() {}) })
+}
+
+// mapKV is a naive in-memory backend for an FFI state KV, used for testing.
+type mapKV struct {
+	sync.Mutex
+	data  map[string][]byte
+	state []byte
+	sets  int
+}
+
+func newMapKV(t *testing.T) (*mapKV, *StateKV_FFI) {
+	kv, err := NewStateKV_FFI()
+	require.NoError(t, err)
+
+	m := &mapKV{data: make(map[string][]byte), state: make([]byte, StateSize)}
+	kv.RegisterGet(func(key []byte) []byte {
+		m.Lock()
+		defer m.Unlock()
+		return m.data[string(key)]
+	})
+	kv.RegisterSet(func(key, value []byte) {
+		m.Lock()
+		defer m.Unlock()
+		m.data[string(key)] = append([]byte(nil), value...)
+		m.sets++
+	})
+	kv.RegisterDiscard(func() {})
+	kv.RegisterCheckpoint(func() []byte {
+		m.Lock()
+		defer m.Unlock()
+		state := sha256.Sum256(append(m.state, byte(m.sets)))
+		m.state = state[:]
+		return m.state
+	})
+	kv.RegisterHead(func() []byte {
+		m.Lock()
+		defer m.Unlock()
+		return m.state
+	})
+
+	return m, kv
+}
+
+func TestStateKV_FFI_MultipleInstances(t *testing.T) {
+	req := require.New(t)
+
+	code, err := ioutil.ReadFile(counterTemplateFilename)
+	req.NoError(err)
+
+	imports := newCounterImports(t)
+	defer imports.Free()
+
+	type instance struct {
+		m       *mapKV
+		kv      *StateKV_FFI
+		runtime Runtime
+		appAddr Address
+	}
+
+	instances := make([]*instance, 2)
+	for i := range instances {
+		m, kv := newMapKV(t)
+		defer kv.Free()
+
+		runtime, err := NewRuntimeBuilder().
+			WithImports(imports).
+			WithStateKV_FFI(kv).
+			Build()
+		req.NoError(err)
+		defer runtime.Free()
+
+		instances[i] = &instance{m: m, kv: kv, runtime: runtime}
+	}
+
+	// Transactions are encoded on this goroutine, so that only the runtimes are used concurrently.
+	parallel := func(f func(i int, inst *instance) error) {
+		var wg sync.WaitGroup
+		errs := make([]error, len(instances))
+		for i, inst := range instances {
+			wg.Add(1)
+			go func(i int, inst *instance) {
+				defer wg.Done()
+				errs[i] = f(i, inst)
+			}(i, inst)
+		}
+		wg.Wait()
+
+		for i := range instances {
+			req.NoError(errs[i])
+		}
+	}
+
+	deployTx, err := codec.EncodeTxDeployTemplate(0, "counter", code, DataLayout{4}.Encode())
+	req.NoError(err)
+
+	templateAddrs := make([]Address, len(instances))
+	parallel(func(i int, inst *instance) error {
+		receipt, err := DeployTemplate(inst.runtime, deployTx, Address{}, false, 0)
+		if err != nil {
+			return err
+		}
+		templateAddrs[i] = receipt.TemplateAddr
+		return nil
+	})
+
+	spawnTxs := make([][]byte, len(instances))
+	for i := range instances {
+		calldata, err := codec.EncodeCallData([]string{"u32"}, []int{10 * (i + 1)})
+		req.NoError(err)
+		spawnTxs[i], err = codec.EncodeTxSpawnApp(0, templateAddrs[i][:], "counter", "initialize", calldata)
+		req.NoError(err)
+	}
+
+	parallel(func(i int, inst *instance) error {
+		receipt, err := SpawnApp(inst.runtime, spawnTxs[i], Address{}, false, 0)
+		if err != nil {
+			return err
+		}
+		inst.appAddr = receipt.AppAddr
+		return nil
+	})
+
+	for _, inst := range instances {
+		req.NotZero(inst.m.sets)
+	}
+
+	// Each app is known only to the runtime which spawned it.
+	tx, err := codec.EncodeTxExecApp(0, instances[0].appAddr[:], "counter_add", nil)
+	req.NoError(err)
+	_, err = ValidateAppTx(instances[1].runtime, tx)
+	req.NoError(err)
+	_, err = ExecApp(instances[1].runtime, tx, instances[1].m.state, false, 0)
+	req.Error(err)
+}
@@ -0,0 +1,446 @@
+package svm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	fileStateKVLogFilename = "state.log"
+
+	logRecordSet        = 1
+	logRecordCheckpoint = 2
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// FileStateKV is a pure-Go, disk-persistent StateKV implementation.
+//
+// Committed changes are kept in an append-only log file, and an in-memory index
+// maps each key to the locations of its committed values in the log, so that
+// past states can be read with `GetAt` as well. Uncommitted changes are kept
+// in memory until `Checkpoint` is called, which appends them to the log as a
+// single batch, followed by a checkpoint record, and syncs the file to disk.
+//
+// The log consists of the following records:
+//
+// set:
+// +-------------------------------------------------------------------+
+// | type = 1 | key length | key       | value length | value          |
+// | (1 byte) | (4 bytes)  | (N bytes) |  (4 bytes)   | (M bytes)      |
+// +-------------------------------------------------------------------+
+//
+// checkpoint:
+// +------------------------------------------+
+// | type = 2 | state root   | batch checksum |
+// | (1 byte) | (32 bytes)   | (4 bytes)      |
+// +------------------------------------------+
+//
+// The batch checksum is the CRC-32C of the batch records, including the checkpoint
+// record type and state root. Lengths and checksum byte order is Big-Endian.
+// State roots are computed the same way as in InMemoryStateKV.
+//
+// When opened, the log is replayed, and a trailing incomplete or corrupted batch
+// (e.g. due to a crash during `Checkpoint`) is truncated, so that the store resumes
+// from the last successful checkpoint. A corrupted batch which is followed by a valid one
+// isn't truncated, since the latter was committed; opening the store fails instead.
+//
+// Since StateKV methods are invoked by SVM and can't return errors, the first I/O failure
+// is recorded and reported by `Err` and `Close`. Once a failure is recorded, `Checkpoint`
+// no longer commits changes and returns the last committed state root. Thus, callers
+// should check `Err` after executing each transaction.
+type FileStateKV struct {
+	mu      sync.RWMutex
+	file    *os.File
+	size    int64
+	index   map[string][]valueLocation
+	pending map[string][]byte
+	head    []byte
+
+	// states maps each committed state root to the log size right after its checkpoint.
+	states map[string]int64
+
+	// err is the first I/O failure, if any.
+	err error
+}
+
+var _ HistoricalStateKV = (*FileStateKV)(nil)
+
+// valueLocation is the location of a value within the log file.
+type valueLocation struct {
+	offset int64
+	length uint32
+}
+
+// OpenFileStateKV opens the store located under `dir`, creating it if it doesn't exist.
+func OpenFileStateKV(dir string) (*FileStateKV, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to open file state KV: %v", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, fileStateKVLogFilename), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file state KV: %v", err)
+	}
+
+	kv := &FileStateKV{
+		file:    file,
+		index:   make(map[string][]valueLocation),
+		pending: make(map[string][]byte),
+		head:    make([]byte, StateSize),
+		states:  make(map[string]int64),
+	}
+	kv.states[string(kv.head)] = 0
+
+	if err := kv.recover(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open file state KV: %v", err)
+	}
+
+	return kv, nil
+}
+
+// recover replays the log, and truncates it after the last valid checkpoint.
+func (kv *FileStateKV) recover() error {
+	info, err := kv.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	r := &logReader{
+		r:    bufio.NewReader(io.NewSectionReader(kv.file, 0, info.Size())),
+		size: info.Size(),
+	}
+	batch := make(map[string][]byte)
+	batchIndex := make(map[string]valueLocation)
+
+	for {
+		err := r.next(func(key []byte, value []byte, valueOffset int64) {
+			batch[string(key)] = value
+			batchIndex[string(key)] = valueLocation{offset: valueOffset, length: uint32(len(value))}
+		}, func(root []byte) error {
+			if expected := nextStateRoot(kv.head, batch); !bytes.Equal(expected, root) {
+				return errors.New("state root mismatch")
+			}
+
+			for k, loc := range batchIndex {
+				kv.index[k] = append(kv.index[k], loc)
+			}
+			kv.head = root
+			kv.size = r.offset
+			kv.states[string(root)] = kv.size
+
+			batch = make(map[string][]byte)
+			batchIndex = make(map[string]valueLocation)
+			return nil
+		})
+		if err != nil {
+			// A crash during `Checkpoint` may only leave the last batch incomplete or corrupted,
+			// possibly followed by any data which reached the disk out of order.
+			// A valid batch following it was committed by a later checkpoint, though,
+			// so the corrupted batch can't be truncated without losing it.
+			valid, validErr := kv.validBatchAfter(kv.size+1, r.size)
+			if validErr != nil {
+				return validErr
+			}
+			if valid {
+				return fmt.Errorf("corrupted log at offset %v: %v", kv.size, err)
+			}
+			break
+		}
+	}
+
+	if info.Size() > kv.size {
+		if err := kv.file.Truncate(kv.size); err != nil {
+			return err
+		}
+		return kv.file.Sync()
+	}
+
+	return nil
+}
+
+// validBatchAfter returns whether a batch with a valid checksum starts at any offset
+// between the given ones.
+func (kv *FileStateKV) validBatchAfter(start, end int64) (bool, error) {
+	for offset := start; offset < end; offset++ {
+		r := &logReader{
+			r:    bufio.NewReader(io.NewSectionReader(kv.file, offset, end-offset)),
+			size: end - offset,
+		}
+
+		var valid bool
+		for !valid {
+			err := r.next(func([]byte, []byte, int64) {}, func([]byte) error {
+				valid = true
+				return nil
+			})
+			if err != nil {
+				// Read failures, unlike parsing ones, don't tell whether the batch is valid.
+				var pathErr *os.PathError
+				if errors.As(err, &pathErr) {
+					return false, err
+				}
+				break
+			}
+		}
+		if valid {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Get returns nil if the value can't be read, and records the failure.
+func (kv *FileStateKV) Get(key []byte) []byte {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if v, ok := kv.pending[string(key)]; ok {
+		return cloneBytes(v)
+	}
+
+	locs := kv.index[string(key)]
+	if len(locs) == 0 {
+		return nil
+	}
+
+	value, err := kv.read(locs[len(locs)-1])
+	if err != nil {
+		kv.fail(err)
+		return nil
+	}
+
+	return value
+}
+
+func (kv *FileStateKV) GetAt(state, key []byte) ([]byte, error) {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	size, ok := kv.states[string(state)]
+	if !ok {
+		return nil, fmt.Errorf("unknown state: %x", state)
+	}
+
+	// The key's values are ordered by their offsets, so the value at `state`
+	// is the last one committed before the log reached `size`.
+	locs := kv.index[string(key)]
+	i := sort.Search(len(locs), func(i int) bool { return locs[i].offset >= size })
+	if i == 0 {
+		return nil, nil
+	}
+
+	return kv.read(locs[i-1])
+}
+
+func (kv *FileStateKV) read(loc valueLocation) ([]byte, error) {
+	value := make([]byte, loc.length)
+	if _, err := kv.file.ReadAt(value, loc.offset); err != nil {
+		return nil, fmt.Errorf("failed to read value: %v", err)
+	}
+
+	return value, nil
+}
+
+func (kv *FileStateKV) Set(key, value []byte) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.pending[string(key)] = cloneBytes(value)
+}
+
+func (kv *FileStateKV) Discard() {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.pending = make(map[string][]byte)
+}
+
+func (kv *FileStateKV) Checkpoint() []byte {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if len(kv.pending) == 0 || kv.err != nil {
+		return cloneBytes(kv.head)
+	}
+
+	keys := make([]string, 0, len(kv.pending))
+	for k := range kv.pending {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+	batchIndex := make(map[string]valueLocation, len(keys))
+	for _, k := range keys {
+		v := kv.pending[k]
+		buf.WriteByte(logRecordSet)
+		writeLengthPrefixed(buf, []byte(k))
+		binary.Write(buf, binary.BigEndian, uint32(len(v)))
+		batchIndex[k] = valueLocation{offset: kv.size + int64(buf.Len()), length: uint32(len(v))}
+		buf.Write(v)
+	}
+
+	root := nextStateRoot(kv.head, kv.pending)
+	buf.WriteByte(logRecordCheckpoint)
+	buf.Write(root)
+	binary.Write(buf, binary.BigEndian, crc32.Checksum(buf.Bytes(), crcTable))
+
+	if _, err := kv.file.WriteAt(buf.Bytes(), kv.size); err != nil {
+		kv.fail(fmt.Errorf("failed to write checkpoint: %v", err))
+		return cloneBytes(kv.head)
+	}
+	if err := kv.file.Sync(); err != nil {
+		kv.fail(fmt.Errorf("failed to sync checkpoint: %v", err))
+		return cloneBytes(kv.head)
+	}
+
+	for k, loc := range batchIndex {
+		kv.index[k] = append(kv.index[k], loc)
+	}
+	kv.size += int64(buf.Len())
+	kv.head = root
+	kv.states[string(root)] = kv.size
+	kv.pending = make(map[string][]byte)
+
+	return cloneBytes(kv.head)
+}
+
+func (kv *FileStateKV) Head() []byte {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	return cloneBytes(kv.head)
+}
+
+// Err returns the first I/O failure of the store, if any.
+func (kv *FileStateKV) Err() error {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	return kv.err
+}
+
+// fail records an I/O failure, unless one was already recorded.
+// It must be called while holding the write lock.
+func (kv *FileStateKV) fail(err error) {
+	if kv.err == nil {
+		kv.err = fmt.Errorf("file state KV: %v", err)
+	}
+}
+
+// Close closes the underlying log file. Uncommitted changes are lost.
+// It returns the first I/O failure of the store, if any.
+func (kv *FileStateKV) Close() error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	closeErr := kv.file.Close()
+	if kv.err != nil {
+		return kv.err
+	}
+
+	return closeErr
+}
+
+// logReader reads the log records sequentially, while tracking the current
+// offset and the checksum of the current batch.
+type logReader struct {
+	r      *bufio.Reader
+	size   int64
+	offset int64
+	crc    uint32
+}
+
+func (lr *logReader) read(n int) ([]byte, error) {
+	// Avoid allocating buffers for corrupted lengths.
+	if int64(n) > lr.size-lr.offset {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(lr.r, b); err != nil {
+		return nil, err
+	}
+
+	lr.offset += int64(n)
+	lr.crc = crc32.Update(lr.crc, crcTable, b)
+	return b, nil
+}
+
+func (lr *logReader) readLength() (uint32, error) {
+	b, err := lr.read(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// next reads the next record, and passes it to the respective callback.
+func (lr *logReader) next(onSet func(key []byte, value []byte, valueOffset int64), onCheckpoint func(root []byte) error) error {
+	ty, err := lr.read(1)
+	if err != nil {
+		return err
+	}
+
+	switch ty[0] {
+	case logRecordSet:
+		keyLen, err := lr.readLength()
+		if err != nil {
+			return err
+		}
+		key, err := lr.read(int(keyLen))
+		if err != nil {
+			return err
+		}
+		valueLen, err := lr.readLength()
+		if err != nil {
+			return err
+		}
+		valueOffset := lr.offset
+		value, err := lr.read(int(valueLen))
+		if err != nil {
+			return err
+		}
+
+		onSet(key, value, valueOffset)
+		return nil
+	case logRecordCheckpoint:
+		root, err := lr.read(StateSize)
+		if err != nil {
+			return err
+		}
+
+		// The checksum itself isn't part of the batch checksum.
+		expectedCrc := lr.crc
+		b := make([]byte, 4)
+		if _, err := io.ReadFull(lr.r, b); err != nil {
+			return err
+		}
+		lr.offset += 4
+		if binary.BigEndian.Uint32(b) != expectedCrc {
+			return errors.New("batch checksum mismatch")
+		}
+
+		if err := onCheckpoint(root); err != nil {
+			return err
+		}
+
+		lr.crc = 0
+		return nil
+	default:
+		return fmt.Errorf("invalid record type: %v", ty[0])
+	}
+}
@@ -0,0 +1,251 @@
+package svm
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "go-svm-file-kv")
+	require.NoError(t, err)
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func kvValue(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KVValueSize)
+}
+
+func TestFileStateKV_Reopen(t *testing.T) {
+	req := require.New(t)
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	kv, err := OpenFileStateKV(dir)
+	req.NoError(err)
+	kv.Set([]byte("a"), kvValue(1))
+	kv.Set([]byte("b"), kvValue(2))
+	state := kv.Checkpoint()
+
+	// Uncommitted changes are lost.
+	kv.Set([]byte("a"), kvValue(3))
+	kv.Set([]byte("c"), kvValue(4))
+	req.NoError(kv.Close())
+
+	kv, err = OpenFileStateKV(dir)
+	req.NoError(err)
+	defer kv.Close()
+
+	req.Equal(state, kv.Head())
+	req.Equal(kvValue(1), kv.Get([]byte("a")))
+	req.Equal(kvValue(2), kv.Get([]byte("b")))
+	req.Nil(kv.Get([]byte("c")))
+
+	// Past states are restored as well.
+	a, err := kv.GetAt(state, []byte("a"))
+	req.NoError(err)
+	req.Equal(kvValue(1), a)
+	a, err = kv.GetAt(make([]byte, StateSize), []byte("a"))
+	req.NoError(err)
+	req.Nil(a)
+
+	// Roots match the in-memory reference implementation.
+	mem := NewInMemoryStateKV()
+	mem.Set([]byte("a"), kvValue(1))
+	mem.Set([]byte("b"), kvValue(2))
+	req.Equal(mem.Checkpoint(), kv.Head())
+}
+
+func TestFileStateKV_RecoverTruncatedLog(t *testing.T) {
+	req := require.New(t)
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	logPath := filepath.Join(dir, fileStateKVLogFilename)
+
+	kv, err := OpenFileStateKV(dir)
+	req.NoError(err)
+	kv.Set([]byte("a"), kvValue(1))
+	state1 := kv.Checkpoint()
+
+	info, err := os.Stat(logPath)
+	req.NoError(err)
+	size1 := info.Size()
+
+	kv.Set([]byte("a"), kvValue(2))
+	kv.Set([]byte("b"), kvValue(3))
+	state2 := kv.Checkpoint()
+	req.NotEqual(state1, state2)
+	req.NoError(kv.Close())
+
+	info, err = os.Stat(logPath)
+	req.NoError(err)
+	size2 := info.Size()
+
+	data, err := ioutil.ReadFile(logPath)
+	req.NoError(err)
+
+	// Simulate a crash at every possible point while writing the second batch.
+	for size := size2 - 1; size > size1; size-- {
+		req.NoError(ioutil.WriteFile(logPath, data[:size], 0644))
+
+		kv, err := OpenFileStateKV(dir)
+		req.NoError(err)
+		req.Equal(state1, kv.Head(), "size: %v", size)
+		req.Equal(kvValue(1), kv.Get([]byte("a")))
+		req.Nil(kv.Get([]byte("b")))
+		req.NoError(kv.Close())
+
+		// The incomplete batch is truncated.
+		info, err := os.Stat(logPath)
+		req.NoError(err)
+		req.Equal(size1, info.Size())
+	}
+
+	// A crash may also leave the file extended with zeros, rather than with the batch data.
+	req.NoError(ioutil.WriteFile(logPath, append(data[:size1:size1], make([]byte, size2-size1)...), 0644))
+	kv, err = OpenFileStateKV(dir)
+	req.NoError(err)
+	req.Equal(state1, kv.Head())
+	req.NoError(kv.Close())
+
+	info, err = os.Stat(logPath)
+	req.NoError(err)
+	req.Equal(size1, info.Size())
+
+	// A crash may also leave the batch followed by garbage, if later sectors reached the disk
+	// before earlier ones. Garbage which doesn't hold a valid batch is truncated as well.
+	garbage := bytes.Repeat([]byte{0x5a}, int(size2-size1))
+	req.NoError(ioutil.WriteFile(logPath, append(data[:size1+10:size1+10], garbage...), 0644))
+	kv, err = OpenFileStateKV(dir)
+	req.NoError(err)
+	req.Equal(state1, kv.Head())
+	req.Nil(kv.Get([]byte("b")))
+	req.NoError(kv.Close())
+
+	info, err = os.Stat(logPath)
+	req.NoError(err)
+	req.Equal(size1, info.Size())
+
+	// The store keeps working after recovery.
+	kv, err = OpenFileStateKV(dir)
+	req.NoError(err)
+	kv.Set([]byte("a"), kvValue(2))
+	kv.Set([]byte("b"), kvValue(3))
+	req.Equal(state2, kv.Checkpoint())
+	req.NoError(kv.Close())
+
+	kv, err = OpenFileStateKV(dir)
+	req.NoError(err)
+	defer kv.Close()
+	req.Equal(state2, kv.Head())
+	req.Equal(kvValue(3), kv.Get([]byte("b")))
+}
+
+func TestFileStateKV_RecoverCorruptedLog(t *testing.T) {
+	req := require.New(t)
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	logPath := filepath.Join(dir, fileStateKVLogFilename)
+
+	kv, err := OpenFileStateKV(dir)
+	req.NoError(err)
+	kv.Set([]byte("a"), kvValue(1))
+	state1 := kv.Checkpoint()
+	kv.Set([]byte("b"), kvValue(2))
+	kv.Checkpoint()
+	req.NoError(kv.Close())
+
+	// Flip a byte of the last value.
+	data, err := ioutil.ReadFile(logPath)
+	req.NoError(err)
+	data[len(data)-1-4-StateSize-1] ^= 0xff
+	req.NoError(ioutil.WriteFile(logPath, data, 0644))
+
+	kv, err = OpenFileStateKV(dir)
+	req.NoError(err)
+	defer kv.Close()
+	req.Equal(state1, kv.Head())
+	req.Nil(kv.Get([]byte("b")))
+}
+
+func TestFileStateKV_CorruptedLogFollowedByData(t *testing.T) {
+	req := require.New(t)
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	logPath := filepath.Join(dir, fileStateKVLogFilename)
+
+	kv, err := OpenFileStateKV(dir)
+	req.NoError(err)
+	kv.Set([]byte("a"), kvValue(1))
+	kv.Checkpoint()
+
+	info, err := os.Stat(logPath)
+	req.NoError(err)
+	size1 := info.Size()
+
+	kv.Set([]byte("b"), kvValue(2))
+	kv.Checkpoint()
+	kv.Set([]byte("c"), kvValue(3))
+	kv.Checkpoint()
+	req.NoError(kv.Close())
+
+	// Flip a byte of the first value.
+	data, err := ioutil.ReadFile(logPath)
+	req.NoError(err)
+	data[size1-1-4-StateSize-1] ^= 0xff
+	req.NoError(ioutil.WriteFile(logPath, data, 0644))
+
+	_, err = OpenFileStateKV(dir)
+	req.EqualError(err, "failed to open file state KV: corrupted log at offset 0: batch checksum mismatch")
+
+	// The later checkpoints aren't truncated.
+	info, err = os.Stat(logPath)
+	req.NoError(err)
+	req.Equal(int64(len(data)), info.Size())
+}
+
+func TestFileStateKV_IOFailure(t *testing.T) {
+	req := require.New(t)
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	kv, err := OpenFileStateKV(dir)
+	req.NoError(err)
+	kv.Set([]byte("a"), kvValue(1))
+	state := kv.Checkpoint()
+	req.NoError(kv.Err())
+
+	// Closing the file underneath the store makes any I/O fail.
+	req.NoError(kv.file.Close())
+
+	req.Nil(kv.Get([]byte("a")))
+	req.EqualError(kv.Err(), "file state KV: failed to read value: read "+kv.file.Name()+": file already closed")
+
+	// Changes are no longer committed, and the first failure is kept.
+	kv.Set([]byte("b"), kvValue(2))
+	req.Equal(state, kv.Checkpoint())
+	req.Equal(state, kv.Head())
+	req.EqualError(kv.Close(), "file state KV: failed to read value: read "+kv.file.Name()+": file already closed")
+
+	// A failure to commit changes is reported the same way.
+	kv, err = OpenFileStateKV(dir)
+	req.NoError(err)
+	defer kv.Close()
+	req.NoError(kv.file.Close())
+
+	kv.Set([]byte("b"), kvValue(2))
+	req.Equal(state, kv.Checkpoint())
+	req.EqualError(kv.Err(), "file state KV: failed to write checkpoint: write "+kv.file.Name()+": file already closed")
+}
@@ -0,0 +1,161 @@
+package svm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// InMemoryStateKV is a reference, in-memory StateKV implementation.
+//
+// Uncommitted changes are kept aside until `Checkpoint` is called. The state root
+// is computed by hashing the previous state root along with the committed changes:
+//
+//     root = SHA256(prev root || change #1 || . . . || change #N)
+//
+// where changes are sorted by key, and each change is encoded as:
+//
+// +-------------------------------------------------------+
+// | key length | key      | value length | value          |
+// | (4 bytes)  | (N bytes)|  (4 bytes)   | (M bytes)      |
+// +-------------------------------------------------------+
+//
+// Lengths byte order is Big-Endian.
+//
+// The changes committed by each checkpoint are kept, so that past states can be read with `GetAt`.
+type InMemoryStateKV struct {
+	mu        sync.RWMutex
+	committed map[string][]byte
+	pending   map[string][]byte
+	head      []byte
+
+	// history holds the changes committed by each checkpoint, in order.
+	history []committedChanges
+}
+
+// committedChanges are the changes committed by a checkpoint, along with the resulting state root.
+type committedChanges struct {
+	root    []byte
+	changes map[string][]byte
+}
+
+var _ HistoricalStateKV = (*InMemoryStateKV)(nil)
+
+func NewInMemoryStateKV() *InMemoryStateKV {
+	return &InMemoryStateKV{
+		committed: make(map[string][]byte),
+		pending:   make(map[string][]byte),
+		head:      make([]byte, StateSize),
+	}
+}
+
+func (kv *InMemoryStateKV) Get(key []byte) []byte {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	if v, ok := kv.pending[string(key)]; ok {
+		return cloneBytes(v)
+	}
+	if v, ok := kv.committed[string(key)]; ok {
+		return cloneBytes(v)
+	}
+
+	return nil
+}
+
+func (kv *InMemoryStateKV) Set(key, value []byte) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.pending[string(key)] = cloneBytes(value)
+}
+
+func (kv *InMemoryStateKV) Discard() {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.pending = make(map[string][]byte)
+}
+
+func (kv *InMemoryStateKV) Checkpoint() []byte {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if len(kv.pending) == 0 {
+		return cloneBytes(kv.head)
+	}
+
+	kv.head = nextStateRoot(kv.head, kv.pending)
+	for k, v := range kv.pending {
+		kv.committed[k] = v
+	}
+	kv.history = append(kv.history, committedChanges{root: kv.head, changes: kv.pending})
+	kv.pending = make(map[string][]byte)
+
+	return cloneBytes(kv.head)
+}
+
+func (kv *InMemoryStateKV) Head() []byte {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	return cloneBytes(kv.head)
+}
+
+func (kv *InMemoryStateKV) GetAt(state, key []byte) ([]byte, error) {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	// last is the index of the checkpoint which committed `state`,
+	// or -1 for the empty state, which precedes all the checkpoints.
+	last := -1
+	for i, c := range kv.history {
+		if bytes.Equal(c.root, state) {
+			last = i
+		}
+	}
+	if last < 0 && !bytes.Equal(make([]byte, StateSize), state) {
+		return nil, fmt.Errorf("unknown state: %x", state)
+	}
+
+	for i := last; i >= 0; i-- {
+		if v, ok := kv.history[i].changes[string(key)]; ok {
+			return cloneBytes(v), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// nextStateRoot computes the state root resulting from committing `changes` on top of `prev`.
+func nextStateRoot(prev []byte, changes map[string][]byte) []byte {
+	keys := make([]string, 0, len(changes))
+	for k := range changes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	h.Write(prev)
+	for _, k := range keys {
+		writeLengthPrefixed(h, []byte(k))
+		writeLengthPrefixed(h, changes[k])
+	}
+
+	return h.Sum(nil)
+}
+
+func writeLengthPrefixed(w io.Writer, b []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+	w.Write(length[:])
+	w.Write(b)
+}
+
+func cloneBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
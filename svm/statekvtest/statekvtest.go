@@ -0,0 +1,199 @@
+// Package statekvtest provides a conformance test suite for `svm.StateKV` implementations.
+//
+// Usage:
+//
+//     func TestMyStateKV(t *testing.T) {
+//         statekvtest.Run(t, func(t *testing.T) svm.StateKV {
+//             return NewMyStateKV(t)
+//         })
+//     }
+//
+package statekvtest
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"go-svm/svm"
+	"testing"
+)
+
+// Run runs the conformance test suite against fresh, empty
+// StateKV instances created by `newKV`. Reads of past states are tested
+// only if the instances implement `svm.HistoricalStateKV`.
+func Run(t *testing.T, newKV func(t *testing.T) svm.StateKV) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, kv svm.StateKV)
+	}{
+		{"EmptyHead", testEmptyHead},
+		{"GetMissing", testGetMissing},
+		{"SetGet", testSetGet},
+		{"SetOverride", testSetOverride},
+		{"Discard", testDiscard},
+		{"Checkpoint", testCheckpoint},
+		{"CheckpointThenDiscard", testCheckpointThenDiscard},
+		{"InputsAreCopied", testInputsAreCopied},
+		{"History", testHistory},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newKV(t))
+		})
+	}
+
+	t.Run("Deterministic", func(t *testing.T) {
+		testDeterministic(t, newKV(t), newKV(t))
+	})
+}
+
+func key(s string) []byte {
+	return []byte(s)
+}
+
+func value(b byte) []byte {
+	return bytes.Repeat([]byte{b}, svm.KVValueSize)
+}
+
+func testEmptyHead(t *testing.T, kv svm.StateKV) {
+	require.Len(t, kv.Head(), svm.StateSize)
+}
+
+func testGetMissing(t *testing.T, kv svm.StateKV) {
+	require.Nil(t, kv.Get(key("missing")))
+}
+
+func testSetGet(t *testing.T, kv svm.StateKV) {
+	req := require.New(t)
+
+	kv.Set(key("a"), value(1))
+	kv.Set(key("b"), value(2))
+	req.Equal(value(1), kv.Get(key("a")))
+	req.Equal(value(2), kv.Get(key("b")))
+}
+
+func testSetOverride(t *testing.T, kv svm.StateKV) {
+	req := require.New(t)
+
+	kv.Set(key("a"), value(1))
+	kv.Checkpoint()
+	kv.Set(key("a"), value(2))
+	req.Equal(value(2), kv.Get(key("a")))
+	kv.Checkpoint()
+	req.Equal(value(2), kv.Get(key("a")))
+}
+
+func testDiscard(t *testing.T, kv svm.StateKV) {
+	req := require.New(t)
+
+	head := kv.Head()
+	kv.Set(key("a"), value(1))
+	kv.Discard()
+	req.Nil(kv.Get(key("a")))
+	req.Equal(head, kv.Head())
+}
+
+func testCheckpoint(t *testing.T, kv svm.StateKV) {
+	req := require.New(t)
+
+	emptyHead := kv.Head()
+
+	kv.Set(key("a"), value(1))
+	state1 := kv.Checkpoint()
+	req.Len(state1, svm.StateSize)
+	req.Equal(state1, kv.Head())
+	req.NotEqual(emptyHead, state1)
+	req.Equal(value(1), kv.Get(key("a")))
+
+	kv.Set(key("b"), value(2))
+	state2 := kv.Checkpoint()
+	req.Len(state2, svm.StateSize)
+	req.Equal(state2, kv.Head())
+	req.NotEqual(state1, state2)
+}
+
+func testCheckpointThenDiscard(t *testing.T, kv svm.StateKV) {
+	req := require.New(t)
+
+	kv.Set(key("a"), value(1))
+	state := kv.Checkpoint()
+
+	kv.Set(key("a"), value(2))
+	kv.Set(key("b"), value(3))
+	kv.Discard()
+
+	req.Equal(value(1), kv.Get(key("a")))
+	req.Nil(kv.Get(key("b")))
+	req.Equal(state, kv.Head())
+}
+
+func testInputsAreCopied(t *testing.T, kv svm.StateKV) {
+	req := require.New(t)
+
+	k := key("a")
+	v := value(1)
+	kv.Set(k, v)
+	k[0] = 'b'
+	v[0] = 2
+
+	req.Equal(value(1), kv.Get(key("a")))
+	req.Nil(kv.Get(key("b")))
+}
+
+func testHistory(t *testing.T, kv svm.StateKV) {
+	req := require.New(t)
+
+	historical, ok := kv.(svm.HistoricalStateKV)
+	if !ok {
+		t.Skip("not a HistoricalStateKV")
+	}
+
+	emptyHead := kv.Head()
+	kv.Set(key("a"), value(1))
+	state1 := kv.Checkpoint()
+	kv.Set(key("a"), value(2))
+	kv.Set(key("b"), value(3))
+	state2 := kv.Checkpoint()
+
+	// Uncommitted changes aren't part of any state.
+	kv.Set(key("a"), value(4))
+
+	for _, c := range []struct {
+		state []byte
+		a, b  []byte
+	}{
+		{emptyHead, nil, nil},
+		{state1, value(1), nil},
+		{state2, value(2), value(3)},
+	} {
+		a, err := historical.GetAt(c.state, key("a"))
+		req.NoError(err)
+		req.Equal(c.a, a)
+
+		b, err := historical.GetAt(c.state, key("b"))
+		req.NoError(err)
+		req.Equal(c.b, b)
+	}
+
+	_, err := historical.GetAt(value(5), key("a"))
+	req.Error(err)
+}
+
+func testDeterministic(t *testing.T, kv1, kv2 svm.StateKV) {
+	req := require.New(t)
+
+	req.Equal(kv1.Head(), kv2.Head())
+
+	for _, kv := range []svm.StateKV{kv1, kv2} {
+		kv.Set(key("a"), value(1))
+		kv.Set(key("b"), value(2))
+		kv.Checkpoint()
+		kv.Set(key("c"), value(3))
+		kv.Discard()
+		kv.Set(key("a"), value(4))
+		kv.Checkpoint()
+	}
+
+	req.Equal(kv1.Head(), kv2.Head())
+}
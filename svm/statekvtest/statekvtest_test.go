@@ -0,0 +1,41 @@
+package statekvtest
+
+import (
+	"go-svm/svm"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestInMemoryStateKV(t *testing.T) {
+	Run(t, func(t *testing.T) svm.StateKV {
+		return svm.NewInMemoryStateKV()
+	})
+}
+
+func TestFileStateKV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-svm-file-kv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	Run(t, func(t *testing.T) svm.StateKV {
+		kvDir, err := ioutil.TempDir(dir, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		kv, err := svm.OpenFileStateKV(kvDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if err := kv.Close(); err != nil {
+				t.Error(err)
+			}
+		})
+
+		return kv
+	})
+}
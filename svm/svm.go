@@ -1,6 +1,7 @@
 package svm
 
 import (
+	"errors"
 	"go-svm/codec"
 	"go-svm/common"
 )
@@ -17,14 +18,27 @@ type (
 	ExecAppReceipt        = common.ReceiptExecApp
 
 	Address = common.Address
+
+	TemplateNotFoundError    = common.TemplateNotFoundError
+	AppNotFoundError         = common.AppNotFoundError
+	CompilationFailedError   = common.CompilationFailedError
+	InstantiationFailedError = common.InstantiationFailedError
+	FunctionNotFoundError    = common.FunctionNotFoundError
+	FunctionFailedError      = common.FunctionFailedError
 )
 
 var (
 	BytesToAddress = common.BytesToAddress
+
+	ErrOutOfGas = common.ErrOutOfGas
 )
 
 func DeployTemplate(runtime Runtime, appTemplate []byte, author Address, gasMetering bool, gasLimit uint64) (*DeployTemplateReceipt, error) {
-	rawReceipt, err := cSvmDeployTemplate(runtime, appTemplate, author, gasMetering, gasLimit)
+	var rawReceipt []byte
+	var err error
+	runtime.withHostContext(&HostContext{}, func() {
+		rawReceipt, err = cSvmDeployTemplate(runtime, appTemplate, author, gasMetering, gasLimit)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -33,16 +47,89 @@ func DeployTemplate(runtime Runtime, appTemplate []byte, author Address, gasMete
 }
 
 func SpawnApp(runtime Runtime, spawnAppData []byte, creator Address, gasMetering bool, gasLimit uint64) (*SpawnAppReceipt, error) {
-	rawReceipt, err := cSvmSpawnApp(runtime, spawnAppData, creator, gasMetering, gasLimit)
+	return spawnApp(runtime, spawnAppData, creator, gasMetering, gasLimit, &HostContext{})
+}
+
+// SpawnAppWithContext is like SpawnApp, but exposes `ctx` to the context-aware
+// host import functions invoked by the app's constructor (see `RegisterFunctionCtx`).
+// The context `TemplateAddr` is set according to the transaction, `Sender` is set to `creator`,
+// and `GasMetering` and `GasLimit` according to the given gas settings.
+func SpawnAppWithContext(runtime Runtime, spawnAppData []byte, creator Address, gasMetering bool, gasLimit uint64, ctx HostContext) (*SpawnAppReceipt, error) {
+	tx, err := codec.DecodeTxSpawnApp(spawnAppData)
+	if err != nil {
+		return nil, &InvalidTxError{Err: err}
+	}
+
+	ctx.AppAddr = Address{}
+	ctx.TemplateAddr = tx.TemplateAddr
+	ctx.Sender = creator
+	ctx.GasMetering = gasMetering
+	ctx.GasLimit = gasLimit
+
+	return spawnApp(runtime, spawnAppData, creator, gasMetering, gasLimit, &ctx)
+}
+
+func spawnApp(runtime Runtime, spawnAppData []byte, creator Address, gasMetering bool, gasLimit uint64, ctx *HostContext) (*SpawnAppReceipt, error) {
+	var rawReceipt []byte
+	var err error
+	runtime.withHostContext(ctx, func() {
+		rawReceipt, err = cSvmSpawnApp(runtime, spawnAppData, creator, gasMetering, gasLimit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err := codec.DecodeReceiptSpawnApp(rawReceipt)
 	if err != nil {
 		return nil, err
 	}
 
-	return codec.DecodeReceiptSpawnApp(rawReceipt)
+	// Record the app's template, to be exposed by `ExecAppWithContext`.
+	if tx, err := codec.DecodeTxSpawnApp(spawnAppData); err == nil && receipt.Success && runtime.apps != nil {
+		runtime.apps.add(receipt.AppAddr, tx.TemplateAddr)
+	}
+
+	return receipt, nil
 }
 
 func ExecApp(runtime Runtime, tx, appState []byte, gasMetering bool, gasLimit uint64) (*ExecAppReceipt, error) {
-	rawReceipt, err := cSvmExecApp(runtime, tx, appState, gasMetering, gasLimit)
+	var rawReceipt []byte
+	var err error
+	runtime.withHostContext(&HostContext{}, func() {
+		rawReceipt, err = cSvmExecApp(runtime, tx, appState, gasMetering, gasLimit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.DecodeReceiptExecApp(rawReceipt)
+}
+
+// ExecAppWithContext is like ExecApp, but exposes `ctx` to the context-aware
+// host import functions invoked during the execution (see `RegisterFunctionCtx`).
+// The context `AppAddr`, `GasMetering` and `GasLimit` fields are set according to
+// the transaction and the given gas settings, while `Sender` and `Value` are kept as given.
+//
+// `TemplateAddr` is set to the app's template if the app was spawned by the runtime.
+// Otherwise, e.g., if the app was spawned before the process restarted, or by another runtime
+// sharing the state, it's kept as given, and the caller is responsible for it, as for `Sender`.
+func ExecAppWithContext(runtime Runtime, tx, appState []byte, gasMetering bool, gasLimit uint64, ctx HostContext) (*ExecAppReceipt, error) {
+	appAddr, err := cSvmValidateTx(runtime, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.AppAddr = appAddr
+	if templateAddr, ok := runtime.apps.template(appAddr); ok {
+		ctx.TemplateAddr = templateAddr
+	}
+	ctx.GasMetering = gasMetering
+	ctx.GasLimit = gasLimit
+
+	var rawReceipt []byte
+	runtime.withHostContext(&ctx, func() {
+		rawReceipt, err = cSvmExecApp(runtime, tx, appState, gasMetering, gasLimit)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -61,3 +148,63 @@ func ValidateApp(runtime Runtime, app []byte) error {
 func ValidateAppTx(runtime Runtime, appTx []byte) (Address, error) {
 	return cSvmValidateTx(runtime, appTx)
 }
+
+// EstimateDeployTemplate returns the estimated gas required for executing
+// a raw `deploy template` transaction.
+//
+// SVM panics when given a malformed transaction, so the transaction is
+// validated first and an *InvalidTxError is returned if it's rejected.
+func EstimateDeployTemplate(runtime Runtime, appTemplate []byte) (uint64, error) {
+	if err := validateBeforeEstimate(appTemplate, func() error {
+		return cSvmValidateTemplate(runtime, appTemplate)
+	}); err != nil {
+		return 0, err
+	}
+
+	return cSvmEstimateDeployTemplate(runtime, appTemplate)
+}
+
+// EstimateSpawnApp returns the estimated gas required for executing
+// a raw `spawn app` transaction.
+//
+// SVM panics when given a malformed transaction, so the transaction is
+// validated first and an *InvalidTxError is returned if it's rejected.
+func EstimateSpawnApp(runtime Runtime, spawnAppData []byte) (uint64, error) {
+	if err := validateBeforeEstimate(spawnAppData, func() error {
+		return cSvmValidateApp(runtime, spawnAppData)
+	}); err != nil {
+		return 0, err
+	}
+
+	return cSvmEstimateSpawnApp(runtime, spawnAppData)
+}
+
+// EstimateExecApp returns the estimated gas required for executing
+// a raw `exec app` transaction.
+//
+// SVM panics when given a malformed transaction, so the transaction is
+// validated first and an *InvalidTxError is returned if it's rejected.
+func EstimateExecApp(runtime Runtime, tx []byte) (uint64, error) {
+	if err := validateBeforeEstimate(tx, func() error {
+		_, err := cSvmValidateTx(runtime, tx)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	return cSvmEstimateExecApp(runtime, tx)
+}
+
+// validateBeforeEstimate makes sure that a raw transaction can be safely
+// handed over to the SVM estimation functions.
+func validateBeforeEstimate(tx []byte, validate func() error) error {
+	if len(tx) == 0 {
+		return &InvalidTxError{Err: errors.New("empty transaction")}
+	}
+
+	if err := validate(); err != nil {
+		return &InvalidTxError{Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package svm
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEstimate_EmptyTx(t *testing.T) {
+	req := require.New(t)
+
+	estimators := map[string]func(Runtime, []byte) (uint64, error){
+		"deploy-template": EstimateDeployTemplate,
+		"spawn-app":       EstimateSpawnApp,
+		"exec-app":        EstimateExecApp,
+	}
+
+	for name, estimate := range estimators {
+		for _, tx := range [][]byte{nil, {}} {
+			estimation, err := estimate(Runtime{}, tx)
+			req.Zero(estimation, name)
+
+			var invalidTxErr *InvalidTxError
+			req.True(errors.As(err, &invalidTxErr), name)
+			req.EqualError(err, "invalid transaction: empty transaction", name)
+		}
+	}
+}
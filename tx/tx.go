@@ -0,0 +1,173 @@
+// Package tx provides fluent builders for raw SVM transactions:
+//
+//	deploy, err := tx.NewDeploy().Name("counter").Code(code).Layout(layout).Build()
+//	spawn, err := tx.NewSpawn(templateAddr).Name("my-counter").Ctor("initialize").Args(codec.U32(10)).Build()
+//	call, err := tx.NewCall(appAddr).Func("counter_add").Args(codec.U32(5)).Build()
+//
+// Arguments are encoded as calldata with `codec.EncodeCallDataValues`,
+// and transactions with the matching `codec.EncodeTx*` function.
+package tx
+
+import (
+	"fmt"
+	"go-svm/codec"
+	"go-svm/common"
+)
+
+// LayoutEncoder encodes a template's data layout, e.g., `svm.Layout` or `svm.DataLayout`.
+type LayoutEncoder interface {
+	Encode() []byte
+}
+
+// DeployBuilder builds a raw deploy-template transaction.
+// The template's name, code and data layout are required.
+type DeployBuilder struct {
+	version int
+	name    string
+	code    []byte
+	layout  LayoutEncoder
+}
+
+// NewDeploy returns a builder of a transaction which deploys a template.
+func NewDeploy() DeployBuilder {
+	return DeployBuilder{}
+}
+
+// Version sets the transaction version, which defaults to 0.
+func (b DeployBuilder) Version(version int) DeployBuilder {
+	b.version = version
+	return b
+}
+
+// Name sets the template's name.
+func (b DeployBuilder) Name(name string) DeployBuilder {
+	b.name = name
+	return b
+}
+
+// Code sets the template's WebAssembly code.
+func (b DeployBuilder) Code(code []byte) DeployBuilder {
+	b.code = code
+	return b
+}
+
+// Layout sets the template's data layout. An empty layout declares no variables.
+func (b DeployBuilder) Layout(layout LayoutEncoder) DeployBuilder {
+	b.layout = layout
+	return b
+}
+
+// Build validates that the name, code and layout are set, and encodes the transaction.
+func (b DeployBuilder) Build() ([]byte, error) {
+	switch {
+	case b.name == "":
+		return nil, fmt.Errorf("invalid deploy-template transaction: missing name")
+	case len(b.code) == 0:
+		return nil, fmt.Errorf("invalid deploy-template transaction: missing code")
+	case b.layout == nil:
+		return nil, fmt.Errorf("invalid deploy-template transaction: missing layout")
+	}
+
+	return codec.EncodeTxDeployTemplate(b.version, b.name, b.code, b.layout.Encode())
+}
+
+// SpawnBuilder builds a raw spawn-app transaction.
+// The constructor's name is required, while the app's name and the constructor's arguments are optional.
+type SpawnBuilder struct {
+	version      int
+	templateAddr common.Address
+	name         string
+	ctorName     string
+	args         []codec.ABIValue
+}
+
+// NewSpawn returns a builder of a transaction which spawns an app of the template at `templateAddr`.
+func NewSpawn(templateAddr common.Address) SpawnBuilder {
+	return SpawnBuilder{templateAddr: templateAddr}
+}
+
+// Version sets the transaction version, which defaults to 0.
+func (b SpawnBuilder) Version(version int) SpawnBuilder {
+	b.version = version
+	return b
+}
+
+// Name sets the spawned app's name.
+func (b SpawnBuilder) Name(name string) SpawnBuilder {
+	b.name = name
+	return b
+}
+
+// Ctor sets the name of the constructor function, which is invoked when spawning the app.
+func (b SpawnBuilder) Ctor(name string) SpawnBuilder {
+	b.ctorName = name
+	return b
+}
+
+// Args sets the constructor's arguments, replacing any previously set ones.
+func (b SpawnBuilder) Args(args ...codec.ABIValue) SpawnBuilder {
+	b.args = args
+	return b
+}
+
+// Build validates that the constructor's name is set, and encodes the transaction,
+// along with the constructor's arguments.
+func (b SpawnBuilder) Build() ([]byte, error) {
+	if b.ctorName == "" {
+		return nil, fmt.Errorf("invalid spawn-app transaction: missing constructor name")
+	}
+
+	calldata, err := codec.EncodeCallDataValues(b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spawn-app transaction: %v", err)
+	}
+
+	return codec.EncodeTxSpawnApp(b.version, b.templateAddr[:], b.name, b.ctorName, calldata)
+}
+
+// CallBuilder builds a raw exec-app transaction.
+// The function's name is required, while its arguments are optional.
+type CallBuilder struct {
+	version  int
+	appAddr  common.Address
+	funcName string
+	args     []codec.ABIValue
+}
+
+// NewCall returns a builder of a transaction which calls a function of the app at `appAddr`.
+func NewCall(appAddr common.Address) CallBuilder {
+	return CallBuilder{appAddr: appAddr}
+}
+
+// Version sets the transaction version, which defaults to 0.
+func (b CallBuilder) Version(version int) CallBuilder {
+	b.version = version
+	return b
+}
+
+// Func sets the name of the called function.
+func (b CallBuilder) Func(name string) CallBuilder {
+	b.funcName = name
+	return b
+}
+
+// Args sets the function's arguments, replacing any previously set ones.
+func (b CallBuilder) Args(args ...codec.ABIValue) CallBuilder {
+	b.args = args
+	return b
+}
+
+// Build validates that the function's name is set, and encodes the transaction,
+// along with the function's arguments.
+func (b CallBuilder) Build() ([]byte, error) {
+	if b.funcName == "" {
+		return nil, fmt.Errorf("invalid exec-app transaction: missing function name")
+	}
+
+	calldata, err := codec.EncodeCallDataValues(b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exec-app transaction: %v", err)
+	}
+
+	return codec.EncodeTxExecApp(b.version, b.appAddr[:], b.funcName, calldata)
+}
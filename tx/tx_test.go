@@ -0,0 +1,108 @@
+package tx
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"go-svm/codec"
+	"go-svm/common"
+	"testing"
+)
+
+var addr = common.BytesToAddress([]byte{
+	0xbc, 0x21, 0x3f, 0xfe, 0x5f, 0x28, 0x5a, 0xdf, 0x9b, 0x2d,
+	0xf9, 0x97, 0x5a, 0x98, 0xa8, 0xf3, 0xb8, 0x10, 0x6b, 0xf7,
+})
+
+// dataLayout encodes variable sizes as `svm.DataLayout` does, without depending on the SVM library.
+type dataLayout []uint32
+
+func (dl dataLayout) Encode() []byte {
+	b := make([]byte, 4*len(dl))
+	for i, size := range dl {
+		binary.BigEndian.PutUint32(b[4*i:], size)
+	}
+	return b
+}
+
+func TestDeployBuilder(t *testing.T) {
+	req := require.New(t)
+
+	raw, err := NewDeploy().Name("counter").Code([]byte{0xaa, 0xbb}).Layout(dataLayout{4, 20}).Build()
+	req.NoError(err)
+
+	tx, err := codec.DecodeTxDeployTemplate(raw)
+	req.NoError(err)
+	req.Equal(&codec.TxDeployTemplate{Name: "counter", Code: []byte{0xaa, 0xbb}, DataLayout: []uint32{4, 20}}, tx)
+
+	expected, err := codec.EncodeTxDeployTemplate(0, "counter", []byte{0xaa, 0xbb}, dataLayout{4, 20}.Encode())
+	req.NoError(err)
+	req.Equal(expected, raw)
+
+	raw, err = NewDeploy().Name("empty").Code([]byte{0xaa}).Layout(dataLayout{}).Build()
+	req.NoError(err)
+	tx, err = codec.DecodeTxDeployTemplate(raw)
+	req.NoError(err)
+	req.Empty(tx.DataLayout)
+}
+
+func TestSpawnBuilder(t *testing.T) {
+	req := require.New(t)
+
+	raw, err := NewSpawn(addr).Name("my-counter").Ctor("initialize").Args(codec.U32(10)).Build()
+	req.NoError(err)
+
+	tx, err := codec.DecodeTxSpawnApp(raw)
+	req.NoError(err)
+	req.Equal(addr, tx.TemplateAddr)
+	req.Equal("my-counter", tx.Name)
+	req.Equal("initialize", tx.CtorName)
+
+	calldata, err := codec.EncodeCallDataValues(codec.U32(10))
+	req.NoError(err)
+	req.Equal(calldata, tx.Calldata)
+
+	raw, err = NewSpawn(addr).Ctor("initialize").Build()
+	req.NoError(err)
+	tx, err = codec.DecodeTxSpawnApp(raw)
+	req.NoError(err)
+	req.Empty(tx.Name)
+	req.Empty(tx.Calldata)
+}
+
+func TestCallBuilder(t *testing.T) {
+	req := require.New(t)
+
+	raw, err := NewCall(addr).Func("counter_add").Args(codec.U32(5), codec.Array{codec.Bool(true)}).Build()
+	req.NoError(err)
+
+	tx, err := codec.DecodeTxExecApp(raw)
+	req.NoError(err)
+	req.Equal(addr, tx.AppAddr)
+	req.Equal("counter_add", tx.FuncName)
+
+	values, err := codec.DecodeReturndataValues(tx.Calldata)
+	req.NoError(err)
+	req.Equal([]codec.ABIValue{codec.U32(5), codec.Array{codec.Bool(true)}}, values)
+}
+
+func TestBuilders_Invalid(t *testing.T) {
+	cases := []struct {
+		build func() ([]byte, error)
+		err   string
+	}{
+		{NewDeploy().Code([]byte{0xaa}).Layout(dataLayout{}).Build, "invalid deploy-template transaction: missing name"},
+		{NewDeploy().Name("counter").Layout(dataLayout{}).Build, "invalid deploy-template transaction: missing code"},
+		{NewDeploy().Name("counter").Code([]byte{0xaa}).Build, "invalid deploy-template transaction: missing layout"},
+		{NewSpawn(addr).Name("my-counter").Args(codec.U32(10)).Build, "invalid spawn-app transaction: missing constructor name"},
+		{NewSpawn(addr).Ctor("initialize").Args(codec.Array{codec.U32(1), codec.Bool(true)}).Build,
+			"invalid spawn-app transaction: invalid calldata value #0: array item #1: mismatching types; expected: u32, given: bool"},
+		{NewCall(addr).Args(codec.U32(5)).Build, "invalid exec-app transaction: missing function name"},
+		{NewCall(addr).Func("counter_add").Args(codec.U32(5), nil).Build,
+			"invalid exec-app transaction: invalid calldata value #1: missing value"},
+	}
+
+	for _, c := range cases {
+		_, err := c.build()
+		require.EqualError(t, err, c.err)
+	}
+}